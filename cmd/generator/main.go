@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"gorchestrator-poc/internal/llm"
 	"gorchestrator-poc/internal/orchestrator"
@@ -18,19 +20,47 @@ import (
 var Version = "dev"
 
 func main() {
+	// With -sandbox the generator re-executes itself as the sandbox helper
+	validator.SandboxMain()
+
 	// Define command-line flags for configuration
 	var (
 		prompt       = flag.String("prompt", "REST API for todo list", "Description of what to generate")
 		output       = flag.String("output", "./generated", "Output directory for generated code")
+		provider     = flag.String("provider", "ollama", "LLM provider for models without an ollama: or openai: prefix (openai is any OpenAI-compatible server, e.g. vLLM or llama.cpp)")
 		ollamaHost   = flag.String("ollama", "http://localhost:11434", "Ollama API endpoint")
-		model        = flag.String("model", "codellama:7b", "LLM model to use for generation")
+		openaiHost   = flag.String("openai", "http://localhost:8000/v1", "OpenAI-compatible API endpoint (API key read from OPENAI_API_KEY)")
+		model        = flag.String("model", "codellama:7b", "LLM model to use; a comma-separated list is tried in order, e.g. codellama:7b,openai:Qwen/Qwen2.5-Coder-7B-Instruct")
 		dbPath       = flag.String("db", "./poc.db", "SQLite database path")
+		pipelinePath = flag.String("pipeline", "", "JSON pipeline definition (default: built-in Todo API pipeline)")
+		promptsDir   = flag.String("prompts", "", "Directory of prompt files overriding the built-in prompts")
+		recordDir    = flag.String("record", "", "Save every LLM request and response to this directory")
+		replayDir    = flag.String("replay", "", "Serve LLM responses from a -record directory instead of a model")
+		noCache      = flag.Bool("no-cache", false, "Always call the LLM instead of reusing cached responses")
+		cacheTTL     = flag.Duration("cache-ttl", 7*24*time.Hour, "Maximum age of cached LLM responses (0 keeps them forever)")
+		workers      = flag.Int("workers", 1, "Number of independent tasks to generate concurrently")
+		candidates   = flag.Int("candidates", 1, "Completions generated per task; the one with the fewest diagnostics is kept")
+		temperature  = flag.Float64("temperature", 0.2, "Sampling temperature for every task (pipeline options take precedence)")
+		topP         = flag.Float64("top-p", 0.9, "Nucleus sampling threshold for every task")
+		numPredict   = flag.Int("num-predict", 4096, "Maximum tokens generated per LLM call")
+		numCtx       = flag.Int("num-ctx", 0, "Context window in tokens (Ollama only; 0 uses the model default)")
+		seed         = flag.Int("seed", 0, "Sampling seed for reproducible output (unset by default)")
 		skipValidate = flag.Bool("skip-validation", false, "Skip code validation after generation")
+		sandbox      = flag.Bool("sandbox", false, "Run the Go toolchain, generated tests and smoke-test server in a Linux sandbox without network access")
+		stageNames   = flag.String("stages", "", "Comma-separated validation stages to run, e.g. format,vet,build,test,coverage,smoke (default: the pipeline's, or format,vet,build,test,smoke)")
+		required     = flag.String("require", "", "Comma-separated validation stages that must pass; the process exits with status 2 otherwise (build is required by default)")
+		repair       = flag.Bool("repair", true, "Type-check each file as it is generated and re-prompt the LLM with errors until the code compiles")
+		stream       = flag.Bool("stream", false, "Print generated tokens live (most readable with -workers 1)")
 		cleanDB      = flag.Bool("clean", false, "Clean database before running (removes old tasks)")
 		version      = flag.Bool("version", false, "Show version information")
 		help         = flag.Bool("help", false, "Show help message")
 	)
 
+	vars := varFlag{}
+	flag.Var(vars, "var", "Prompt template variable as key=value (repeatable)")
+	stageTimeouts := varFlag{}
+	flag.Var(stageTimeouts, "stage-timeout", "Timeout of a validation stage as stage=duration, e.g. test=5m (repeatable)")
+
 	flag.Parse()
 
 	// Handle version flag
@@ -45,9 +75,48 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Sampling flags given on the command line apply to every task
+	genOptions := generationOptions(*temperature, *topP, *numPredict, *numCtx, *seed)
+	if err := genOptions.Validate(); err != nil {
+		log.Fatal("ERROR: Invalid generation options: ", err)
+	}
+
+	if *recordDir != "" && *replayDir != "" {
+		log.Fatal("ERROR: -record and -replay cannot be combined")
+	}
+
+	// Parse the optional "resume <run-id>" command
+	resumeID := ""
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "resume" || len(args) != 2 {
+			fmt.Println("Usage: overnight-llm [flags] resume <run-id>")
+			os.Exit(2)
+		}
+		resumeID = args[1]
+		if *cleanDB {
+			log.Fatal("ERROR: -clean cannot be combined with resume (it deletes the run)")
+		}
+		// The run's stored pipeline and project are used
+		for _, name := range []string{"pipeline", "prompt"} {
+			if flagSet(name) {
+				log.Fatalf("ERROR: -%s cannot be combined with resume (the run's stored %s is used)", name, name)
+			}
+		}
+	}
+
 	// Print startup banner
 	printBanner()
 
+	// Load the pipeline definition before touching the database or the LLM server
+	pipeline := orchestrator.DefaultPipeline()
+	if *pipelinePath != "" {
+		p, err := orchestrator.LoadPipeline(*pipelinePath)
+		if err != nil {
+			log.Fatal("ERROR: Failed to load pipeline:", err)
+		}
+		pipeline = p
+	}
+
 	// Initialize database with embedded schema
 	fmt.Println("Initializing database...")
 	db, err := storage.InitDB(*dbPath)
@@ -66,42 +135,169 @@ func main() {
 		fmt.Println("Database cleaned successfully")
 	}
 
-	// Create Ollama client for LLM interactions
-	fmt.Printf("Connecting to Ollama at %s...\n", *ollamaHost)
-	ollamaClient := llm.NewOllamaClient(*ollamaHost, *model)
+	// A resumed run uses its stored pipeline and, unless -output moves it, its output directory
+	if resumeID != "" {
+		run, err := storage.NewStorage(db).GetRun(resumeID)
+		if err != nil {
+			log.Fatal("ERROR: Cannot resume:", err)
+		}
+		if pipeline, err = orchestrator.ParsePipeline([]byte(run.Pipeline)); err != nil {
+			log.Fatal("ERROR: Cannot resume:", err)
+		}
+		if !flagSet("output") {
+			*output = run.WorkDir
+		}
+		*prompt = run.Project
+	}
+
+	// Resolve the validation stages now so mistakes surface before generation
+	stages, err := validationStages(pipeline, *stageNames, *required, stageTimeouts)
+	if err != nil {
+		log.Fatal("ERROR: Invalid validation stages: ", err)
+	}
+
+	// Create the LLM client for the primary model, or replay a recording
+	providers := providerConfig{
+		provider:   *provider,
+		ollamaHost: *ollamaHost,
+		openaiHost: *openaiHost,
+		apiKey:     os.Getenv("OPENAI_API_KEY"),
+		recordDir:  *recordDir,
+		replayDir:  *replayDir,
+	}
+	models := splitModels(*model)
+	if len(models) == 0 {
+		log.Fatal("ERROR: -model names no model")
+	}
+	for i, ref := range models {
+		models[i] = providers.canonicalRef(ref)
+	}
+	primaryProvider, primaryModel := providers.parseModelRef(models[0])
+
+	switch {
+	case *replayDir != "":
+		fmt.Printf("Replaying LLM responses from %s (no model is contacted)...\n", *replayDir)
+	case primaryProvider == "openai":
+		fmt.Printf("Connecting to OpenAI-compatible API at %s...\n", *openaiHost)
+	default:
+		fmt.Printf("Connecting to Ollama at %s...\n", *ollamaHost)
+	}
+	client, err := providers.newProvider(models[0])
+	if err != nil {
+		log.Fatal("ERROR: ", err)
+	}
+	if *recordDir != "" {
+		fmt.Printf("Recording LLM responses to %s\n", *recordDir)
+	}
 
-	// Perform health check to ensure Ollama is running and model is available
-	fmt.Printf("Checking model availability (%s)...\n", *model)
+	// Perform health check to ensure the server is running and model is available
+	fmt.Printf("Checking model availability (%s)...\n", primaryModel)
 	ctx := context.Background()
-	if err := ollamaClient.HealthCheck(ctx); err != nil {
-		fmt.Println("ERROR: Ollama health check failed:", err)
+	if err := client.HealthCheck(ctx); err != nil {
+		fmt.Printf("ERROR: %s health check failed: %v\n", primaryProvider, err)
 		fmt.Println("\n📋 Prerequisites:")
+		if primaryProvider == "openai" {
+			fmt.Println("  1. Start an OpenAI-compatible server, for example:")
+			fmt.Printf("     vllm serve %s\n", primaryModel)
+			fmt.Printf("     llama-server -m model.gguf --alias %s --port 8000\n", primaryModel)
+			fmt.Println("  2. Point -openai at its base URL")
+			os.Exit(1)
+		}
 		fmt.Println("  1. Start Ollama service:")
 		fmt.Printf("     ollama serve\n")
 		fmt.Println("  2. Pull the required model:")
-		fmt.Printf("     ollama pull %s\n", *model)
+		fmt.Printf("     ollama pull %s\n", primaryModel)
 		fmt.Println("\nTip: For faster generation, try smaller models like:")
 		fmt.Println("     ollama pull codellama:7b")
 		fmt.Println("     ollama pull deepseek-coder:1.3b")
 		os.Exit(1)
 	}
-	fmt.Println("Ollama is ready!")
+
+	// Fallback models are only needed if the primary fails, so don't stop for them
+	for _, ref := range models[1:] {
+		fallback, err := providers.newProvider(ref)
+		if err == nil {
+			err = fallback.HealthCheck(ctx)
+		}
+		if err != nil {
+			fmt.Printf("WARNING: Fallback model %s unavailable: %v\n", ref, err)
+		}
+	}
+	fmt.Println("LLM is ready!")
 
 	// Create orchestrator to manage the generation pipeline
-	orch := orchestrator.New(ollamaClient, db, *output)
+	orch := orchestrator.New(client, db, *output)
+	orch.SetWorkers(*workers)
+	orch.SetCandidates(*candidates)
+	orch.SetVars(vars)
+	orch.SetPromptsDir(*promptsDir)
+	orch.SetOptions(genOptions)
+	orch.SetProgress(newProgressPrinter(*stream).update)
+	orch.SetModels(models, providers.newProvider)
+
+	// Cache responses in the database unless a recording is being made or replayed,
+	// which must see every request
+	if !*noCache && *recordDir == "" && *replayDir == "" {
+		if err := orch.EnableCache(models[0], *cacheTTL); err != nil {
+			log.Fatal("ERROR: Failed to enable response cache: ", err)
+		}
+	}
+
+	// Every toolchain run on generated code goes through the sandbox if requested
+	newValidator := func() *validator.Validator {
+		val := validator.NewValidator(*output)
+		if *sandbox {
+			if err := val.EnableSandbox(validator.DefaultSandboxLimits); err != nil {
+				log.Fatal("ERROR: ", err)
+			}
+		}
+		return val
+	}
+	if *sandbox {
+		newValidator() // Fail before generating anything if the sandbox can't be used
+	}
+
+	// Type-check each task's output in-process, and enable the compile-error
+	// repair loop when the Go toolchain is available
+	if *repair {
+		orch.SetChecker(validator.NewChecker(*output))
+
+		val := newValidator()
+		if err := val.CheckGoInstallation(); err != nil {
+			fmt.Printf("WARNING: Go not found, only in-process type checking enabled: %v\n", err)
+		} else {
+			orch.SetValidator(val)
+		}
+	}
 
 	// Start the generation process
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	fmt.Printf("STARTING CODE GENERATION\n")
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Printf("Task:        %s\n", *prompt)
+	fmt.Printf("Pipeline:    %s (%d tasks)\n", pipeline.Name, len(pipeline.Tasks))
 	fmt.Printf("Output:      %s\n", *output)
-	fmt.Printf("Model:       %s\n", *model)
+	fmt.Printf("Model:       %s\n", strings.Join(models, " → "))
+	if *replayDir != "" {
+		fmt.Printf("Replay:      %s\n", *replayDir)
+	}
+	fmt.Printf("Workers:     %d\n", *workers)
+	if *candidates > 1 {
+		fmt.Printf("Candidates:  %d per task\n", *candidates)
+	}
+	if *promptsDir != "" {
+		fmt.Printf("Prompts:     %s (built-in fallback)\n", *promptsDir)
+	}
 	fmt.Printf("Database:    %s\n", *dbPath)
 	fmt.Println(strings.Repeat("=", 60) + "\n")
 
-	// Run the main generation pipeline
-	if err := orch.GenerateTodoAPI(ctx, *prompt); err != nil {
+	// Run the main generation pipeline, or continue an interrupted one
+	if resumeID != "" {
+		err = orch.Resume(ctx, resumeID)
+	} else {
+		err = orch.Generate(ctx, pipeline, *prompt)
+	}
+	if err != nil {
 		fmt.Printf("\nERROR: Generation failed: %v\n", err)
 
 		// Don't show success summary on failure - show what went wrong
@@ -111,6 +307,9 @@ func main() {
 		fmt.Printf("Error: %v\n", err)
 		fmt.Printf("Output Dir: %s\n", *output)
 		fmt.Printf("Database: %s\n", *dbPath)
+		if orch.RunID() != "" {
+			fmt.Printf("Resume with: overnight-llm -db %s resume %s\n", *dbPath, orch.RunID())
+		}
 
 		// Provide troubleshooting tips
 		fmt.Println("\nTroubleshooting tips:")
@@ -124,29 +323,45 @@ func main() {
 	}
 
 	// Run validation if not skipped
+	var failedGates []validator.ValidationResult
 	if !*skipValidate {
 		fmt.Println("\n" + strings.Repeat("=", 60))
 		fmt.Println("VALIDATING GENERATED CODE")
 		fmt.Println(strings.Repeat("=", 60))
 
-		val := validator.NewValidator(*output)
+		val := newValidator()
 
 		// Check Go installation first
 		if err := val.CheckGoInstallation(); err != nil {
 			fmt.Printf("WARNING: Go not found: %v\n", err)
 			fmt.Println("   Skipping validation (Go required for validation)")
+			for _, stage := range stages {
+				if stage.Required {
+					failedGates = append(failedGates, validator.ValidationResult{Tool: stage.Name, Required: true})
+				}
+			}
 		} else {
-			// Run all validation checks
-			results := val.ValidateAll(ctx)
-			validator.PrintResults(results)
-
-			// Optionally format the code
+			// Format first so the format stage checks what is left on disk
 			fmt.Println("\nAuto-formatting generated code...")
 			if err := val.FormatCode(ctx); err != nil {
 				fmt.Printf("WARNING: Failed to format code: %v\n", err)
 			} else {
 				fmt.Println("Code formatted successfully")
 			}
+
+			// Run the configured validation stages
+			results := val.RunStages(ctx, stages)
+			for _, result := range results {
+				orch.AttributeDiagnostics(result.Diagnostics)
+				if result.Stage == validator.StageTest && result.Tests != nil {
+					if err := orch.RecordTestResults(result); err != nil {
+						fmt.Printf("WARNING: Test results not recorded: %v\n", err)
+					}
+				}
+			}
+			validator.PrintResults(results)
+			failedGates = validator.FailedGates(results)
+
 		}
 	}
 
@@ -155,6 +370,112 @@ func main() {
 
 	// Print next steps for the user
 	printNextSteps(*output)
+
+	// Fail the run when a required validation stage did not pass
+	if len(failedGates) > 0 {
+		os.Exit(2)
+	}
+}
+
+// validationStages resolves the stages to run from the pipeline (or the
+// defaults), the -stages selection, -require and -stage-timeout
+func validationStages(pipeline *orchestrator.Pipeline, names, required string, timeouts varFlag) ([]validator.Stage, error) {
+	configured := validator.DefaultStages
+	if pipeline != nil && len(pipeline.Validation) > 0 {
+		configured = pipeline.Validation
+	}
+
+	stages, err := validator.SelectStages(configured, splitList(names))
+	if err != nil {
+		return nil, err
+	}
+	// Copy so the flags never modify the defaults or the pipeline
+	stages = append([]validator.Stage(nil), stages...)
+
+	index := make(map[string]int, len(stages))
+	for i, s := range stages {
+		index[s.Name] = i
+	}
+	for _, name := range splitList(required) {
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("required stage %s is not selected", name)
+		}
+		stages[i].Required = true
+	}
+	for name, timeout := range timeouts {
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("timeout for stage %s which is not selected", name)
+		}
+		stages[i].Timeout = timeout
+	}
+	return stages, validator.ValidateStages(stages)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// varFlag collects repeated -var key=value flags
+type varFlag map[string]string
+
+// String returns the variables in key=value form
+func (v varFlag) String() string {
+	pairs := make([]string, 0, len(v))
+	for key, value := range v {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a single key=value pair
+func (v varFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	v[key] = value
+	return nil
+}
+
+// generationOptions builds LLM options from the sampling flags given explicitly
+// Flags left at their defaults don't override the built-in options
+func generationOptions(temperature, topP float64, numPredict, numCtx, seed int) llm.Options {
+	var opts llm.Options
+	if flagSet("temperature") {
+		opts.Temperature = llm.Float(temperature)
+	}
+	if flagSet("top-p") {
+		opts.TopP = llm.Float(topP)
+	}
+	if flagSet("num-predict") {
+		opts.NumPredict = numPredict
+	}
+	opts.NumCtx = numCtx
+	if flagSet("seed") {
+		opts.Seed = llm.Int(seed)
+	}
+	return opts
+}
+
+// flagSet reports whether a flag was given explicitly on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 // printBanner displays the application banner
@@ -174,6 +495,7 @@ func printHelp() {
 	fmt.Println("Overnight LLM Code Generator - Proof of Concept")
 	fmt.Println("\nUsage:")
 	fmt.Println("  overnight-llm [flags]")
+	fmt.Println("  overnight-llm [flags] resume <run-id>")
 	fmt.Println("\nFlags:")
 	flag.PrintDefaults()
 	fmt.Println("\nExamples:")
@@ -188,6 +510,37 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("  # Skip validation for faster generation")
 	fmt.Println("  ./overnight-llm -skip-validation")
+	fmt.Println()
+	fmt.Println("  # Generate a project from a custom pipeline definition")
+	fmt.Println("  ./overnight-llm -pipeline ./my-pipeline.json")
+	fmt.Println()
+	fmt.Println("  # Watch the code being written")
+	fmt.Println("  ./overnight-llm -stream")
+	fmt.Println()
+	fmt.Println("  # Use a vLLM or llama.cpp server instead of Ollama")
+	fmt.Println("  ./overnight-llm -provider openai -openai http://localhost:8000/v1 -model Qwen/Qwen2.5-Coder-7B-Instruct")
+	fmt.Println()
+	fmt.Println("  # Fall back to a larger model when the first keeps failing")
+	fmt.Println("  ./overnight-llm -model codellama:7b,codellama:13b")
+	fmt.Println()
+	fmt.Println("  # Spend more compute: keep the best of 4 completions per task")
+	fmt.Println("  ./overnight-llm -candidates 4 -temperature 0.6")
+	fmt.Println()
+	fmt.Println("  # Override some of the built-in prompts")
+	fmt.Println("  ./overnight-llm -prompts ./my-prompts")
+	fmt.Println()
+	fmt.Println("  # Pass variables to prompt templates")
+	fmt.Println("  ./overnight-llm -pipeline ./my-pipeline.json -var entity=Book -var table=books")
+	fmt.Println()
+	fmt.Println("  # Regenerate from scratch, ignoring cached responses")
+	fmt.Println("  ./overnight-llm -no-cache")
+	fmt.Println()
+	fmt.Println("  # Record a run, then replay it offline against the same responses")
+	fmt.Println("  ./overnight-llm -record ./recordings")
+	fmt.Println("  ./overnight-llm -replay ./recordings -output ./replayed")
+	fmt.Println()
+	fmt.Println("  # Continue an interrupted run, skipping completed tasks")
+	fmt.Println("  ./overnight-llm resume run_1755633455")
 	fmt.Println("\nPrerequisites:")
 	fmt.Println("  1. Install and start Ollama: https://ollama.ai")
 	fmt.Println("  2. Pull a code generation model: ollama pull codellama:7b")
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gorchestrator-poc/internal/orchestrator"
+)
+
+// progressInterval is how often a running task reports its token count
+const progressInterval = 5 * time.Second
+
+// progressPrinter shows live progress of streaming LLM calls
+// With showTokens the generated text itself is echoed, which is only
+// readable when a single worker is generating
+type progressPrinter struct {
+	showTokens bool
+	mu         sync.Mutex
+	lastPrint  map[string]time.Time // Last progress line per task
+}
+
+// newProgressPrinter creates a printer for orchestrator progress updates
+func newProgressPrinter(showTokens bool) *progressPrinter {
+	return &progressPrinter{
+		showTokens: showTokens,
+		lastPrint:  make(map[string]time.Time),
+	}
+}
+
+// update handles a single progress update
+func (p *progressPrinter) update(prog orchestrator.Progress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if prog.Done {
+		delete(p.lastPrint, prog.Task.ID)
+		if p.showTokens {
+			fmt.Print(prog.Text + "\n")
+		}
+		fmt.Printf("    %s: %d tokens in %v (%.1f tok/s)\n",
+			prog.Task.Type, prog.Tokens, prog.Elapsed.Round(100*time.Millisecond), prog.TokensPerSecond())
+		return
+	}
+
+	if p.showTokens {
+		fmt.Print(prog.Text)
+		return
+	}
+
+	// Throttle progress lines so concurrent tasks stay readable
+	last, ok := p.lastPrint[prog.Task.ID]
+	if !ok {
+		p.lastPrint[prog.Task.ID] = time.Now()
+		return
+	}
+	if time.Since(last) >= progressInterval {
+		p.lastPrint[prog.Task.ID] = time.Now()
+		fmt.Printf("    %s: %d tokens so far (%.1f tok/s)\n",
+			prog.Task.Type, prog.Tokens, prog.TokensPerSecond())
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/orchestrator"
+)
+
+// providerConfig holds the flags needed to create an LLM client for any model
+type providerConfig struct {
+	provider   string // Provider for model references without a prefix
+	ollamaHost string
+	openaiHost string
+	apiKey     string
+	recordDir  string // Wraps every client in a recorder when set
+	replayDir  string // Replaces every client with a replayer when set
+}
+
+// parseModelRef splits a "[provider:]model" reference
+// Only known provider names count as a prefix, so "codellama:7b" is a model
+func (c providerConfig) parseModelRef(ref string) (provider, model string) {
+	prefix, rest, ok := strings.Cut(ref, ":")
+	if ok && (prefix == "ollama" || prefix == "openai") {
+		return prefix, rest
+	}
+	return c.provider, ref
+}
+
+// canonicalRef returns a reference with its provider made explicit
+func (c providerConfig) canonicalRef(ref string) string {
+	provider, model := c.parseModelRef(ref)
+	return provider + ":" + model
+}
+
+// newProvider creates the client serving a model reference
+func (c providerConfig) newProvider(ref string) (orchestrator.LLMProvider, error) {
+	if c.replayDir != "" {
+		return llm.NewReplayer(c.replayDir), nil
+	}
+
+	var client interface {
+		orchestrator.LLMProvider
+		llm.ChatClient
+	}
+	provider, model := c.parseModelRef(ref)
+	switch provider {
+	case "ollama":
+		client = llm.NewOllamaClient(c.ollamaHost, model)
+	case "openai":
+		client = llm.NewOpenAIClient(c.openaiHost, model, c.apiKey)
+	default:
+		return nil, fmt.Errorf("unknown provider %q (expected ollama or openai)", provider)
+	}
+
+	if c.recordDir != "" {
+		return llm.NewRecorder(client, c.recordDir)
+	}
+	return client, nil
+}
+
+// splitModels parses a comma-separated model chain, dropping empty entries
+func splitModels(list string) []string {
+	var models []string
+	for _, model := range strings.Split(list, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
@@ -2,4 +2,7 @@ module gorchestrator-poc
 
 go 1.24.5
 
-require github.com/mattn/go-sqlite3 v1.14.32
+require (
+	github.com/mattn/go-sqlite3 v1.14.32
+	golang.org/x/sys v0.38.0
+)
@@ -0,0 +1,14 @@
+package llm
+
+// Message roles understood by chat endpoints
+const (
+	RoleSystem    = "system"    // Standing instructions for the model
+	RoleUser      = "user"      // Requests from the orchestrator
+	RoleAssistant = "assistant" // Earlier replies from the model
+)
+
+// Message is a single turn of a chat conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
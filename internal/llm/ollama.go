@@ -4,17 +4,22 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 // OllamaClient provides a simple HTTP client for interacting with Ollama API
-// It handles code generation requests without streaming for simplicity
+// Complete and Chat wait for the whole response; the Stream variants deliver it
+// token by token
 type OllamaClient struct {
-	endpoint string       // Base URL for Ollama API (e.g., "http://localhost:11434")
-	model    string       // Model to use for generation (e.g., "codellama:7b")
-	client   *http.Client // HTTP client with timeout configuration
+	endpoint    string        // Base URL for Ollama API (e.g., "http://localhost:11434")
+	model       string        // Model to use for generation (e.g., "codellama:7b")
+	client      *http.Client  // HTTP client; requests are bounded by their context
+	idleTimeout time.Duration // Longest a stream may go without data
 }
 
 // NewOllamaClient creates a new Ollama API client
@@ -22,12 +27,33 @@ func NewOllamaClient(endpoint, model string) *OllamaClient {
 	return &OllamaClient{
 		endpoint: endpoint,
 		model:    model,
-		client: &http.Client{
-			Timeout: 5 * time.Minute, // Generous timeout for code generation
-		},
+		// No overall timeout: streamed generations run as long as the context
+		// allows, and idleTimeout catches stalled streams
+		client:      &http.Client{},
+		idleTimeout: defaultIdleTimeout,
 	}
 }
 
+// StatusError reports a non-200 HTTP response from an LLM API
+// Server-side failures are marked temporary so callers can retry them
+type StatusError struct {
+	Provider   string // Name of the API that responded (e.g. "Ollama")
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Provider, e.StatusCode)
+}
+
+// Temporary reports whether the request may succeed if retried
+// Covers 5xx server errors and 429 rate limiting
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// ErrIncomplete is returned when the model stops before finishing its response
+var ErrIncomplete = errors.New("generation incomplete")
+
 // generateRequest represents the request payload for Ollama's generate endpoint
 type generateRequest struct {
 	Model   string                 `json:"model"`
@@ -36,11 +62,21 @@ type generateRequest struct {
 	Options map[string]interface{} `json:"options"`
 }
 
-// generateResponse represents the response from Ollama's generate endpoint
+// chatRequest represents the request payload for Ollama's chat endpoint
+type chatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options"`
+}
+
+// generateResponse represents the response from Ollama's generate and chat
+// endpoints. Generate fills Response, chat fills Message
 type generateResponse struct {
 	Model              string    `json:"model"`
 	CreatedAt          time.Time `json:"created_at"`
 	Response           string    `json:"response"`
+	Message            *Message  `json:"message,omitempty"`
 	Done               bool      `json:"done"`
 	Context            []int     `json:"context,omitempty"`
 	TotalDuration      int64     `json:"total_duration,omitempty"`
@@ -49,61 +85,157 @@ type generateResponse struct {
 	PromptEvalDuration int64     `json:"prompt_eval_duration,omitempty"`
 	EvalCount          int       `json:"eval_count,omitempty"`
 	EvalDuration       int64     `json:"eval_duration,omitempty"`
+	Error              string    `json:"error,omitempty"` // Set when a stream fails mid-generation
+}
+
+// text returns the generated text of a response or chunk
+func (r *generateResponse) text() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
 }
 
 // Complete sends a prompt to Ollama and returns the generated response
 // Uses non-streaming mode for simplicity and waits for complete response
-func (o *OllamaClient) Complete(ctx context.Context, prompt string) (string, error) {
-	// Prepare request payload with low temperature for consistent code generation
-	payload := generateRequest{
-		Model:  o.model,
-		Prompt: prompt,
-		Stream: false, // Wait for complete response
-		Options: map[string]interface{}{
-			"temperature": 0.2,  // Low temperature for deterministic code generation
-			"top_p":       0.9,  // Slightly limit token pool for quality
-			"num_predict": 4096, // Max tokens to generate
-		},
+func (o *OllamaClient) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
+	return o.complete(ctx, "/api/generate", generateRequest{
+		Model:   o.model,
+		Prompt:  prompt,
+		Stream:  false, // Wait for complete response
+		Options: opts.ollamaOptions(),
+	})
+}
+
+// CompleteStream sends a prompt to Ollama and passes each chunk of the
+// response to fn as it is generated. Returns the full response once done
+func (o *OllamaClient) CompleteStream(ctx context.Context, prompt string, opts Options, fn StreamFunc) (string, error) {
+	return o.stream(ctx, "/api/generate", generateRequest{
+		Model:   o.model,
+		Prompt:  prompt,
+		Stream:  true,
+		Options: opts.ollamaOptions(),
+	}, fn)
+}
+
+// Chat sends a conversation to Ollama's chat endpoint and returns the reply
+// The model's chat template keeps system instructions apart from user text
+func (o *OllamaClient) Chat(ctx context.Context, messages []Message, opts Options) (string, error) {
+	return o.complete(ctx, "/api/chat", chatRequest{
+		Model:    o.model,
+		Messages: messages,
+		Stream:   false,
+		Options:  opts.ollamaOptions(),
+	})
+}
+
+// ChatStream sends a conversation to Ollama's chat endpoint and passes each
+// chunk of the reply to fn as it is generated
+func (o *OllamaClient) ChatStream(ctx context.Context, messages []Message, opts Options, fn StreamFunc) (string, error) {
+	return o.stream(ctx, "/api/chat", chatRequest{
+		Model:    o.model,
+		Messages: messages,
+		Stream:   true,
+		Options:  opts.ollamaOptions(),
+	}, fn)
+}
+
+// complete posts a non-streaming request and decodes the single response
+func (o *OllamaClient) complete(ctx context.Context, path string, payload interface{}) (string, error) {
+	resp, err := o.post(ctx, path, payload)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Decode response
+	var result generateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Verify generation completed
+	if !result.Done {
+		return "", ErrIncomplete
 	}
 
+	return result.text(), nil
+}
+
+// stream posts a streaming request and hands each decoded chunk to fn
+func (o *OllamaClient) stream(ctx context.Context, path string, payload interface{}, fn StreamFunc) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := o.post(ctx, path, payload)
+	if err != nil {
+		return "", err
+	}
+	body := newIdleReader(resp.Body, o.idleTimeout, cancel)
+	defer body.Close()
+
+	// Ollama streams one JSON object per line
+	var full strings.Builder
+	decoder := json.NewDecoder(body)
+	for {
+		var chunk generateResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				// The stream ended without a final chunk
+				return "", ErrIncomplete
+			}
+			return "", fmt.Errorf("failed to decode stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("Ollama stream failed: %s", chunk.Error)
+		}
+
+		full.WriteString(chunk.text())
+		if err := fn(Chunk{
+			Text:         chunk.text(),
+			Done:         chunk.Done,
+			EvalCount:    chunk.EvalCount,
+			EvalDuration: time.Duration(chunk.EvalDuration),
+		}); err != nil {
+			return "", err
+		}
+
+		if chunk.Done {
+			return full.String(), nil
+		}
+	}
+}
+
+// post sends a JSON request to an Ollama endpoint and checks the status
+// The caller must close the response body
+func (o *OllamaClient) post(ctx context.Context, path string, payload interface{}) (*http.Response, error) {
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request with context for cancellation support
-	url := fmt.Sprintf("%s/api/generate", o.endpoint)
+	url := o.endpoint + path
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send request to Ollama
 	resp, err := o.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to Ollama: %w", err)
+		return nil, fmt.Errorf("failed to send request to Ollama: %w", err)
 	}
-	defer resp.Body.Close()
 
 	// Check for non-200 status codes
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Ollama returned status %d", resp.StatusCode)
-	}
-
-	// Decode response
-	var result generateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	// Verify generation completed
-	if !result.Done {
-		return "", fmt.Errorf("generation incomplete")
+		resp.Body.Close()
+		return nil, &StatusError{Provider: "Ollama", StatusCode: resp.StatusCode}
 	}
 
-	return result.Response, nil
+	return resp, nil
 }
 
 // tagsResponse represents the response from Ollama's tags endpoint
@@ -3,6 +3,8 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -28,8 +30,12 @@ func TestNewOllamaClient(t *testing.T) {
 		t.Error("HTTP client should not be nil")
 	}
 
-	if client.client.Timeout != 5*time.Minute {
-		t.Errorf("Expected timeout of 5 minutes, got %v", client.client.Timeout)
+	// Streamed generations are only bounded by their context and idle timeout
+	if client.client.Timeout != 0 {
+		t.Errorf("Expected no overall timeout, got %v", client.client.Timeout)
+	}
+	if client.idleTimeout != 5*time.Minute {
+		t.Errorf("Expected an idle timeout of 5 minutes, got %v", client.idleTimeout)
 	}
 }
 
@@ -103,7 +109,7 @@ func TestComplete(t *testing.T) {
 
 			// Call Complete
 			ctx := context.Background()
-			response, err := client.Complete(ctx, "test prompt")
+			response, err := client.Complete(ctx, "test prompt", DefaultOptions())
 
 			// Check error
 			if tt.wantError {
@@ -233,7 +239,7 @@ func TestCompleteWithTimeout(t *testing.T) {
 	defer cancel()
 
 	// Call should timeout
-	_, err := client.Complete(ctx, "test prompt")
+	_, err := client.Complete(ctx, "test prompt", DefaultOptions())
 	if err == nil {
 		t.Error("Expected timeout error, got nil")
 	}
@@ -264,7 +270,7 @@ func TestRequestPayload(t *testing.T) {
 	ctx := context.Background()
 
 	prompt := "generate some code"
-	client.Complete(ctx, prompt)
+	client.Complete(ctx, prompt, DefaultOptions())
 
 	// Verify payload
 	if capturedPayload.Model != "testmodel" {
@@ -289,6 +295,169 @@ func TestRequestPayload(t *testing.T) {
 	}
 }
 
+// TestCompleteStream tests decoding of streamed NDJSON responses
+func TestCompleteStream(t *testing.T) {
+	errAbort := errors.New("too much output")
+
+	tests := []struct {
+		name          string
+		lines         []string
+		abortAfter    int // Abort from the callback after this many chunks (0 = never)
+		wantResponse  string
+		wantChunks    int
+		errorContains string
+	}{
+		{
+			name: "successful stream",
+			lines: []string{
+				`{"response":"package ","done":false}`,
+				`{"response":"main","done":false}`,
+				`{"response":"","done":true,"eval_count":2,"eval_duration":1000000000}`,
+			},
+			wantResponse: "package main",
+			wantChunks:   3,
+		},
+		{
+			name: "stream ends early",
+			lines: []string{
+				`{"response":"package ","done":false}`,
+			},
+			wantChunks:    1,
+			errorContains: "generation incomplete",
+		},
+		{
+			name: "error mid-stream",
+			lines: []string{
+				`{"response":"package ","done":false}`,
+				`{"error":"model crashed"}`,
+			},
+			wantChunks:    1,
+			errorContains: "model crashed",
+		},
+		{
+			name: "aborted by callback",
+			lines: []string{
+				`{"response":"package ","done":false}`,
+				`{"response":"main","done":false}`,
+				`{"response":"","done":true}`,
+			},
+			abortAfter:    1,
+			wantChunks:    1,
+			errorContains: "too much output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload generateRequest
+				json.NewDecoder(r.Body).Decode(&payload)
+				if !payload.Stream {
+					t.Error("Stream should be true")
+				}
+
+				w.WriteHeader(http.StatusOK)
+				for _, line := range tt.lines {
+					fmt.Fprintln(w, line)
+				}
+			}))
+			defer server.Close()
+
+			client := NewOllamaClient(server.URL, "codellama:7b")
+
+			var chunks []Chunk
+			response, err := client.CompleteStream(context.Background(), "test prompt", DefaultOptions(), func(chunk Chunk) error {
+				chunks = append(chunks, chunk)
+				if tt.abortAfter > 0 && len(chunks) >= tt.abortAfter {
+					return errAbort
+				}
+				return nil
+			})
+
+			if len(chunks) != tt.wantChunks {
+				t.Errorf("Expected %d chunks, got %d", tt.wantChunks, len(chunks))
+			}
+
+			if tt.errorContains != "" {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if !contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing '%s', got '%s'", tt.errorContains, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response != tt.wantResponse {
+				t.Errorf("Expected response '%s', got '%s'", tt.wantResponse, response)
+			}
+
+			last := chunks[len(chunks)-1]
+			if !last.Done || last.EvalCount != 2 || last.EvalDuration != time.Second {
+				t.Errorf("Expected final chunk with statistics, got %+v", last)
+			}
+		})
+	}
+}
+
+// TestChat verifies conversations are sent to the chat endpoint with their roles
+func TestChat(t *testing.T) {
+	var captured chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected path /api/chat, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&captured)
+
+		if captured.Stream {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"package "},"done":false}`)
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"main"},"done":false}`)
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"eval_count":2}`)
+			return
+		}
+		json.NewEncoder(w).Encode(generateResponse{
+			Message: &Message{Role: RoleAssistant, Content: "package main"},
+			Done:    true,
+		})
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL, "codellama:7b")
+	messages := []Message{
+		{Role: RoleSystem, Content: "You write Go."},
+		{Role: RoleUser, Content: "Write main."},
+	}
+
+	response, err := client.Chat(context.Background(), messages, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response != "package main" {
+		t.Errorf("Expected 'package main', got '%s'", response)
+	}
+	if len(captured.Messages) != 2 || captured.Messages[0].Role != RoleSystem || captured.Messages[1].Content != "Write main." {
+		t.Errorf("Unexpected messages sent: %+v", captured.Messages)
+	}
+	if captured.Options["temperature"] != 0.2 {
+		t.Errorf("Expected temperature 0.2, got %v", captured.Options["temperature"])
+	}
+
+	var streamed string
+	response, err = client.ChatStream(context.Background(), messages, DefaultOptions(), func(chunk Chunk) error {
+		streamed += chunk.Text
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected stream error: %v", err)
+	}
+	if response != "package main" || streamed != "package main" {
+		t.Errorf("Expected streamed 'package main', got '%s' ('%s')", response, streamed)
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(substr) > 0 && len(s) >= len(substr) && s[:len(s)] != "" &&
@@ -303,3 +472,38 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// TestStreamIdleTimeout verifies a slow but steady stream outlasts the idle
+// timeout while a stalled one is abandoned with a temporary error
+func TestStreamIdleTimeout(t *testing.T) {
+	stall := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 5; i++ {
+			fmt.Fprintln(w, `{"response":"x","done":false}`)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+		if stall {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintln(w, `{"response":"","done":true}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL, "codellama:7b")
+	client.idleTimeout = 150 * time.Millisecond
+
+	response, err := client.CompleteStream(context.Background(), "test prompt", DefaultOptions(), func(Chunk) error { return nil })
+	if err != nil || response != "xxxxx" {
+		t.Fatalf("Expected the slow stream to finish, got %q (%v)", response, err)
+	}
+
+	stall = true
+	_, err = client.CompleteStream(context.Background(), "test prompt", DefaultOptions(), func(Chunk) error { return nil })
+	var stalled *stallError
+	if !errors.As(err, &stalled) || !stalled.Temporary() {
+		t.Errorf("Expected a temporary stall error, got %v", err)
+	}
+}
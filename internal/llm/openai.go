@@ -0,0 +1,279 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIClient talks to servers implementing the OpenAI chat completions API,
+// such as vLLM and llama.cpp's server
+type OpenAIClient struct {
+	endpoint    string        // Base URL including the version (e.g., "http://localhost:8000/v1")
+	model       string        // Model to use for generation
+	apiKey      string        // Optional bearer token; local servers usually need none
+	client      *http.Client  // HTTP client; requests are bounded by their context
+	idleTimeout time.Duration // Longest a stream may go without data
+}
+
+// NewOpenAIClient creates a new client for an OpenAI-compatible API
+// A missing "/v1" suffix is added to the endpoint
+func NewOpenAIClient(endpoint, model, apiKey string) *OpenAIClient {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1") {
+		endpoint += "/v1"
+	}
+
+	return &OpenAIClient{
+		endpoint: endpoint,
+		model:    model,
+		apiKey:   apiKey,
+		// No overall timeout: streamed generations run as long as the context
+		// allows, and idleTimeout catches stalled streams
+		client:      &http.Client{},
+		idleTimeout: defaultIdleTimeout,
+	}
+}
+
+// openAIRequest represents the request payload for the chat completions endpoint
+// NumCtx has no equivalent; the context size is fixed when the server starts
+type openAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	Stream      bool      `json:"stream"`
+}
+
+// openAIResponse represents a chat completion, or one chunk of a streamed one
+type openAIResponse struct {
+	Choices []struct {
+		Message      Message `json:"message"` // Non-streaming responses
+		Delta        Message `json:"delta"`   // Streaming chunks
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+// Complete sends a prompt as a single user message and returns the reply
+func (c *OpenAIClient) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
+	return c.Chat(ctx, []Message{{Role: RoleUser, Content: prompt}}, opts)
+}
+
+// CompleteStream streams the reply to a prompt sent as a single user message
+func (c *OpenAIClient) CompleteStream(ctx context.Context, prompt string, opts Options, fn StreamFunc) (string, error) {
+	return c.ChatStream(ctx, []Message{{Role: RoleUser, Content: prompt}}, opts, fn)
+}
+
+// Chat sends a conversation and returns the assistant's reply
+func (c *OpenAIClient) Chat(ctx context.Context, messages []Message, opts Options) (string, error) {
+	resp, err := c.chat(ctx, messages, opts, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("response contains no choices")
+	}
+
+	// The model hit max_tokens before finishing
+	choice := result.Choices[0]
+	if choice.FinishReason == "length" {
+		return "", ErrIncomplete
+	}
+
+	return choice.Message.Content, nil
+}
+
+// ChatStream sends a conversation and passes each chunk of the reply to fn as
+// it is generated. Returns the full reply once the server reports completion
+func (c *OpenAIClient) ChatStream(ctx context.Context, messages []Message, opts Options, fn StreamFunc) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := c.chat(ctx, messages, opts, true)
+	if err != nil {
+		return "", err
+	}
+	body := newIdleReader(resp.Body, c.idleTimeout, cancel)
+	defer body.Close()
+
+	// Server-sent events: "data: {json}" lines, terminated by "data: [DONE]"
+	start := time.Now()
+	var full strings.Builder
+	finished := false
+	tokens := 0
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+
+		if data == "[DONE]" {
+			if !finished {
+				return "", ErrIncomplete
+			}
+			if err := fn(Chunk{Done: true, EvalCount: tokens, EvalDuration: time.Since(start)}); err != nil {
+				return "", err
+			}
+			return full.String(), nil
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to decode stream: %w", err)
+		}
+		if chunk.Usage != nil {
+			tokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason == "length" {
+			return "", ErrIncomplete
+		}
+		if choice.FinishReason != "" {
+			finished = true
+		}
+		if choice.Delta.Content == "" {
+			continue
+		}
+
+		if chunk.Usage == nil {
+			tokens++
+		}
+		full.WriteString(choice.Delta.Content)
+		if err := fn(Chunk{Text: choice.Delta.Content}); err != nil {
+			return "", err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	// The connection closed without [DONE]
+	return "", ErrIncomplete
+}
+
+// chat posts a request to the chat completions endpoint and checks the status
+// The caller must close the response body
+func (c *OpenAIClient) chat(ctx context.Context, messages []Message, opts Options, stream bool) (*http.Response, error) {
+	payload := openAIRequest{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.NumPredict,
+		Seed:        opts.Seed,
+		Stream:      stream,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, "POST", "/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", c.endpoint, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{Provider: "OpenAI API", StatusCode: resp.StatusCode}
+	}
+
+	return resp, nil
+}
+
+// newRequest builds a request against the API with the auth header set
+func (c *OpenAIClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+// modelsResponse represents the response from the models endpoint
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// ListModels returns the IDs of the models the server is serving
+func (c *OpenAIClient) ListModels(ctx context.Context) ([]string, error) {
+	req, err := c.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use a shorter timeout for listing models
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API not reachable at %s: %w", c.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing models failed with status %d", resp.StatusCode)
+	}
+
+	var models modelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("failed to decode models list: %w", err)
+	}
+
+	ids := make([]string, 0, len(models.Data))
+	for _, model := range models.Data {
+		ids = append(ids, model.ID)
+	}
+	return ids, nil
+}
+
+// HealthCheck verifies the server is reachable and serves the configured model
+func (c *OpenAIClient) HealthCheck(ctx context.Context) error {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, model := range models {
+		if model == c.model {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %s not served (available: %s)", c.model, strings.Join(models, ", "))
+}
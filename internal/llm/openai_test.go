@@ -0,0 +1,268 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newOpenAIStub starts a stub OpenAI-compatible server serving the given models
+func newOpenAIStub(t *testing.T, models []string, chat http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		var resp modelsResponse
+		for _, id := range models {
+			resp.Data = append(resp.Data, struct {
+				ID string `json:"id"`
+			}{ID: id})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	if chat != nil {
+		mux.HandleFunc("/v1/chat/completions", chat)
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestNewOpenAIClient verifies endpoint normalisation
+func TestNewOpenAIClient(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		expected string
+	}{
+		{"http://localhost:8000", "http://localhost:8000/v1"},
+		{"http://localhost:8000/", "http://localhost:8000/v1"},
+		{"http://localhost:8000/v1", "http://localhost:8000/v1"},
+		{"http://localhost:8000/v1/", "http://localhost:8000/v1"},
+	}
+
+	for _, tt := range tests {
+		client := NewOpenAIClient(tt.endpoint, "model", "")
+		if client.endpoint != tt.expected {
+			t.Errorf("NewOpenAIClient(%q) endpoint = %q, want %q", tt.endpoint, client.endpoint, tt.expected)
+		}
+	}
+}
+
+// TestOpenAIComplete tests the Complete method with various scenarios
+func TestOpenAIComplete(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		want          string
+		errorContains string
+	}{
+		{
+			name:   "successful completion",
+			status: http.StatusOK,
+			body:   `{"choices":[{"message":{"role":"assistant","content":"package main"},"finish_reason":"stop"}]}`,
+			want:   "package main",
+		},
+		{
+			name:          "truncated by max_tokens",
+			status:        http.StatusOK,
+			body:          `{"choices":[{"message":{"role":"assistant","content":"package"},"finish_reason":"length"}]}`,
+			errorContains: "generation incomplete",
+		},
+		{
+			name:          "no choices",
+			status:        http.StatusOK,
+			body:          `{"choices":[]}`,
+			errorContains: "no choices",
+		},
+		{
+			name:          "server error",
+			status:        http.StatusServiceUnavailable,
+			errorContains: "status 503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured openAIRequest
+			server := newOpenAIStub(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+				}
+				json.NewDecoder(r.Body).Decode(&captured)
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			client := NewOpenAIClient(server.URL, "qwen2.5-coder", "secret")
+			response, err := client.Complete(context.Background(), "test prompt", DefaultOptions())
+
+			if tt.errorContains != "" {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if !contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing '%s', got '%s'", tt.errorContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response != tt.want {
+				t.Errorf("Expected response '%s', got '%s'", tt.want, response)
+			}
+
+			// Options are mapped onto the chat completions request
+			if captured.Model != "qwen2.5-coder" || captured.Stream {
+				t.Errorf("Unexpected request: %+v", captured)
+			}
+			if len(captured.Messages) != 1 || captured.Messages[0].Role != "user" || captured.Messages[0].Content != "test prompt" {
+				t.Errorf("Expected a single user message, got %+v", captured.Messages)
+			}
+			if *captured.Temperature != 0.2 || *captured.TopP != 0.9 || captured.MaxTokens != 4096 || captured.Seed != nil {
+				t.Errorf("Unexpected sampling options: %+v", captured)
+			}
+		})
+	}
+}
+
+// TestOpenAICompleteStream tests decoding of server-sent event streams
+func TestOpenAICompleteStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		events        []string
+		want          string
+		wantTokens    int
+		errorContains string
+	}{
+		{
+			name: "successful stream",
+			events: []string{
+				`{"choices":[{"delta":{"role":"assistant"}}]}`,
+				`{"choices":[{"delta":{"content":"package "}}]}`,
+				`{"choices":[{"delta":{"content":"main"}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+				`[DONE]`,
+			},
+			want:       "package main",
+			wantTokens: 2,
+		},
+		{
+			name: "usage reported by server",
+			events: []string{
+				`{"choices":[{"delta":{"content":"package main"}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+				`{"choices":[],"usage":{"completion_tokens":3}}`,
+				`[DONE]`,
+			},
+			want:       "package main",
+			wantTokens: 3,
+		},
+		{
+			name: "truncated by max_tokens",
+			events: []string{
+				`{"choices":[{"delta":{"content":"package "}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"length"}]}`,
+				`[DONE]`,
+			},
+			errorContains: "generation incomplete",
+		},
+		{
+			name: "connection closed early",
+			events: []string{
+				`{"choices":[{"delta":{"content":"package "}}]}`,
+			},
+			errorContains: "generation incomplete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newOpenAIStub(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				var req openAIRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if !req.Stream {
+					t.Error("Stream should be true")
+				}
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range tt.events {
+					fmt.Fprintf(w, "data: %s\n\n", event)
+				}
+			})
+
+			client := NewOpenAIClient(server.URL, "qwen2.5-coder", "")
+			var last Chunk
+			var text string
+			response, err := client.CompleteStream(context.Background(), "test prompt", DefaultOptions(), func(chunk Chunk) error {
+				text += chunk.Text
+				last = chunk
+				return nil
+			})
+
+			if tt.errorContains != "" {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if !contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing '%s', got '%s'", tt.errorContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response != tt.want || text != tt.want {
+				t.Errorf("Expected response '%s', got '%s' (streamed '%s')", tt.want, response, text)
+			}
+			if !last.Done || last.EvalCount != tt.wantTokens {
+				t.Errorf("Expected final chunk with %d tokens, got %+v", tt.wantTokens, last)
+			}
+		})
+	}
+
+	// Errors from the callback abort the stream
+	server := newOpenAIStub(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\n\n")
+	})
+	errAbort := errors.New("abort")
+	client := NewOpenAIClient(server.URL, "qwen2.5-coder", "")
+	if _, err := client.CompleteStream(context.Background(), "p", DefaultOptions(), func(Chunk) error { return errAbort }); !errors.Is(err, errAbort) {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+}
+
+// TestOpenAIHealthCheck tests model listing and the health check
+func TestOpenAIHealthCheck(t *testing.T) {
+	server := newOpenAIStub(t, []string{"qwen2.5-coder", "llama3"}, nil)
+
+	client := NewOpenAIClient(server.URL, "llama3", "")
+	models, err := client.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list models: %v", err)
+	}
+	if len(models) != 2 || models[0] != "qwen2.5-coder" {
+		t.Errorf("Unexpected models: %v", models)
+	}
+
+	if err := client.HealthCheck(context.Background()); err != nil {
+		t.Errorf("Unexpected health check error: %v", err)
+	}
+
+	missing := NewOpenAIClient(server.URL, "codellama:7b", "")
+	err = missing.HealthCheck(context.Background())
+	if err == nil || !contains(err.Error(), "model codellama:7b not served") {
+		t.Errorf("Expected missing model error, got %v", err)
+	}
+
+	unreachable := NewOpenAIClient("http://127.0.0.1:1", "llama3", "")
+	if err := unreachable.HealthCheck(context.Background()); err == nil {
+		t.Error("Expected error for unreachable server, got nil")
+	}
+}
@@ -0,0 +1,91 @@
+package llm
+
+import "fmt"
+
+// Options are the sampling settings sent with a generation request
+// Nil and zero fields are unset; Merge layers more specific settings on top
+type Options struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"` // Maximum tokens to generate
+	NumCtx      int      `json:"num_ctx,omitempty"`     // Context window in tokens (Ollama only)
+	Seed        *int     `json:"seed,omitempty"`        // Fixed seed for reproducible output
+}
+
+// DefaultOptions returns the settings used when nothing else is configured
+// Low temperature keeps code generation close to deterministic
+func DefaultOptions() Options {
+	return Options{
+		Temperature: Float(0.2),
+		TopP:        Float(0.9),
+		NumPredict:  4096,
+	}
+}
+
+// Merge returns o with every field that is set in override replaced
+func (o Options) Merge(override Options) Options {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.NumPredict != 0 {
+		o.NumPredict = override.NumPredict
+	}
+	if override.NumCtx != 0 {
+		o.NumCtx = override.NumCtx
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	return o
+}
+
+// Validate rejects values no backend accepts
+func (o Options) Validate() error {
+	if o.Temperature != nil && *o.Temperature < 0 {
+		return fmt.Errorf("temperature must not be negative: %v", *o.Temperature)
+	}
+	if o.TopP != nil && (*o.TopP <= 0 || *o.TopP > 1) {
+		return fmt.Errorf("top_p must be in (0, 1]: %v", *o.TopP)
+	}
+	if o.NumPredict < 0 {
+		return fmt.Errorf("num_predict must not be negative: %d", o.NumPredict)
+	}
+	if o.NumCtx < 0 {
+		return fmt.Errorf("num_ctx must not be negative: %d", o.NumCtx)
+	}
+	return nil
+}
+
+// ollamaOptions maps the options onto Ollama's options object
+func (o Options) ollamaOptions() map[string]interface{} {
+	opts := make(map[string]interface{})
+	if o.Temperature != nil {
+		opts["temperature"] = *o.Temperature
+	}
+	if o.TopP != nil {
+		opts["top_p"] = *o.TopP
+	}
+	if o.NumPredict != 0 {
+		opts["num_predict"] = o.NumPredict
+	}
+	if o.NumCtx != 0 {
+		opts["num_ctx"] = o.NumCtx
+	}
+	if o.Seed != nil {
+		opts["seed"] = *o.Seed
+	}
+	return opts
+}
+
+// Float returns a pointer to v, for setting optional fields
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to v, for setting optional fields
+func Int(v int) *int {
+	return &v
+}
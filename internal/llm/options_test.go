@@ -0,0 +1,74 @@
+package llm
+
+import "testing"
+
+// TestOptionsMerge verifies set fields override and unset fields are kept
+func TestOptionsMerge(t *testing.T) {
+	base := DefaultOptions()
+
+	merged := base.Merge(Options{Temperature: Float(0), NumCtx: 8192, Seed: Int(42)})
+	if *merged.Temperature != 0 {
+		t.Errorf("Expected explicit zero temperature to override, got %v", *merged.Temperature)
+	}
+	if *merged.TopP != 0.9 || merged.NumPredict != 4096 {
+		t.Errorf("Unset fields should keep their base values, got %+v", merged)
+	}
+	if merged.NumCtx != 8192 || *merged.Seed != 42 {
+		t.Errorf("Expected num_ctx and seed to be set, got %+v", merged)
+	}
+
+	// The base is not modified
+	if base.NumCtx != 0 || base.Seed != nil {
+		t.Errorf("Base options were modified: %+v", base)
+	}
+}
+
+// TestOllamaOptions verifies options map onto Ollama's option names
+func TestOllamaOptions(t *testing.T) {
+	opts := DefaultOptions().Merge(Options{NumCtx: 16384, Seed: Int(7)}).ollamaOptions()
+
+	expected := map[string]interface{}{
+		"temperature": 0.2,
+		"top_p":       0.9,
+		"num_predict": 4096,
+		"num_ctx":     16384,
+		"seed":        7,
+	}
+	for key, want := range expected {
+		if opts[key] != want {
+			t.Errorf("Expected %s = %v, got %v", key, want, opts[key])
+		}
+	}
+
+	// Unset options are left to the model's defaults
+	if len(Options{}.ollamaOptions()) != 0 {
+		t.Error("Expected no options for an empty Options")
+	}
+}
+
+// TestOptionsValidate verifies out-of-range values are rejected
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"defaults", DefaultOptions(), false},
+		{"empty", Options{}, false},
+		{"greedy", Options{Temperature: Float(0)}, false},
+		{"negative temperature", Options{Temperature: Float(-0.1)}, true},
+		{"zero top_p", Options{TopP: Float(0)}, true},
+		{"top_p above one", Options{TopP: Float(1.5)}, true},
+		{"negative num_predict", Options{NumPredict: -1}, true},
+		{"negative num_ctx", Options{NumCtx: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
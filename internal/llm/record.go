@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotRecorded is returned by a Replayer for a request it has no recording of
+var ErrNotRecorded = errors.New("no recorded response")
+
+// ChatClient is the part of a provider the recorder forwards to
+// Implemented by OllamaClient and OpenAIClient
+type ChatClient interface {
+	ChatStream(ctx context.Context, messages []Message, opts Options, fn StreamFunc) (string, error)
+	HealthCheck(ctx context.Context) error
+}
+
+// Recording is a single request and the response the model gave to it
+type Recording struct {
+	Key        string    `json:"key"`
+	Messages   []Message `json:"messages"`
+	Options    Options   `json:"options"`
+	Response   string    `json:"response"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// RecordingKey identifies a request by the SHA-256 of its messages and options
+// Plain prompts are keyed as a single user message, so Complete and Chat share keys
+func RecordingKey(messages []Message, opts Options) string {
+	data, _ := json.Marshal(struct {
+		Messages []Message `json:"messages"`
+		Options  Options   `json:"options"`
+	}{messages, opts})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Recorder wraps a client and saves every successful response to a directory
+// One JSON file is written per request, named after its key
+type Recorder struct {
+	client ChatClient
+	dir    string
+}
+
+// NewRecorder creates a recorder writing to dir, creating it if needed
+func NewRecorder(client ChatClient, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	return &Recorder{client: client, dir: dir}, nil
+}
+
+// Complete sends a prompt through the wrapped client and records the response
+func (r *Recorder) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
+	return r.ChatStream(ctx, userMessage(prompt), opts, discardChunks)
+}
+
+// CompleteStream streams a prompt through the wrapped client and records the response
+func (r *Recorder) CompleteStream(ctx context.Context, prompt string, opts Options, fn StreamFunc) (string, error) {
+	return r.ChatStream(ctx, userMessage(prompt), opts, fn)
+}
+
+// ChatStream streams a conversation through the wrapped client and records the response
+func (r *Recorder) ChatStream(ctx context.Context, messages []Message, opts Options, fn StreamFunc) (string, error) {
+	response, err := r.client.ChatStream(ctx, messages, opts, fn)
+	if err != nil {
+		return "", err
+	}
+
+	rec := Recording{
+		Key:        RecordingKey(messages, opts),
+		Messages:   messages,
+		Options:    opts,
+		Response:   response,
+		RecordedAt: time.Now(),
+	}
+	if err := r.save(rec); err != nil {
+		return "", err
+	}
+	return response, nil
+}
+
+// HealthCheck checks the wrapped client
+func (r *Recorder) HealthCheck(ctx context.Context) error {
+	return r.client.HealthCheck(ctx)
+}
+
+// save writes a recording atomically so concurrent workers never see partial files
+func (r *Recorder) save(rec Recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recording: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(r.dir, ".recording-*")
+	if err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), recordingPath(r.dir, rec.Key)); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// Replayer serves responses saved by a Recorder without contacting any model
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer creates a replayer reading recordings from dir
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// Complete returns the recorded response to a prompt
+func (r *Replayer) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
+	return r.ChatStream(ctx, userMessage(prompt), opts, discardChunks)
+}
+
+// CompleteStream returns the recorded response to a prompt as a single chunk
+func (r *Replayer) CompleteStream(ctx context.Context, prompt string, opts Options, fn StreamFunc) (string, error) {
+	return r.ChatStream(ctx, userMessage(prompt), opts, fn)
+}
+
+// ChatStream returns the recorded response to a conversation as a single chunk
+func (r *Replayer) ChatStream(ctx context.Context, messages []Message, opts Options, fn StreamFunc) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	key := RecordingKey(messages, opts)
+	data, err := os.ReadFile(recordingPath(r.dir, key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w for request %s", ErrNotRecorded, key[:12])
+		}
+		return "", fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return "", fmt.Errorf("failed to decode recording %s: %w", key[:12], err)
+	}
+
+	if err := fn(Chunk{Text: rec.Response, Done: true}); err != nil {
+		return "", err
+	}
+	return rec.Response, nil
+}
+
+// HealthCheck verifies the recording directory exists
+func (r *Replayer) HealthCheck(ctx context.Context) error {
+	info, err := os.Stat(r.dir)
+	if err != nil {
+		return fmt.Errorf("recording directory not readable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("recording path %s is not a directory", r.dir)
+	}
+	return nil
+}
+
+// recordingPath returns the file a recording with the given key is stored in
+func recordingPath(dir, key string) string {
+	return filepath.Join(dir, key+".json")
+}
+
+// userMessage wraps a plain prompt as a single user message
+func userMessage(prompt string) []Message {
+	return []Message{{Role: RoleUser, Content: prompt}}
+}
+
+// discardChunks ignores streamed chunks for callers that only want the result
+func discardChunks(Chunk) error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// fakeChatClient returns a canned response and counts calls
+type fakeChatClient struct {
+	response string
+	err      error
+	calls    int
+}
+
+func (f *fakeChatClient) ChatStream(ctx context.Context, messages []Message, opts Options, fn StreamFunc) (string, error) {
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	if err := fn(Chunk{Text: f.response, Done: true}); err != nil {
+		return "", err
+	}
+	return f.response, nil
+}
+
+func (f *fakeChatClient) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// TestRecordReplay verifies responses recorded from a client are served back offline
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	client := &fakeChatClient{response: "package models"}
+
+	recorder, err := NewRecorder(client, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	messages := []Message{
+		{Role: RoleSystem, Content: "Write Go code"},
+		{Role: RoleUser, Content: "Generate models"},
+	}
+	opts := DefaultOptions()
+
+	if _, err := recorder.ChatStream(ctx, messages, opts, discardChunks); err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if _, err := recorder.Complete(ctx, "Generate handlers", opts); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 recordings, found %d files", len(entries))
+	}
+
+	replayer := NewReplayer(dir)
+	if err := replayer.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck failed: %v", err)
+	}
+
+	var streamed string
+	got, err := replayer.ChatStream(ctx, messages, opts, func(c Chunk) error {
+		streamed += c.Text
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if got != "package models" || streamed != got {
+		t.Errorf("Expected recorded response, got %q (streamed %q)", got, streamed)
+	}
+
+	// A plain prompt replays through the chat path and vice versa
+	if _, err := replayer.ChatStream(ctx, userMessage("Generate handlers"), opts, discardChunks); err != nil {
+		t.Errorf("Expected Complete recording to replay through ChatStream: %v", err)
+	}
+
+	// Different options are a different request
+	_, err = replayer.ChatStream(ctx, messages, opts.Merge(Options{Seed: Int(1)}), discardChunks)
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded for changed options, got %v", err)
+	}
+
+	if client.calls != 2 {
+		t.Errorf("Expected 2 calls to the wrapped client, got %d", client.calls)
+	}
+}
+
+// TestRecorderSkipsFailures verifies failed requests are not recorded
+func TestRecorderSkipsFailures(t *testing.T) {
+	dir := t.TempDir()
+	client := &fakeChatClient{err: &StatusError{Provider: "Ollama", StatusCode: 500}}
+
+	recorder, err := NewRecorder(client, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	if _, err := recorder.Complete(context.Background(), "prompt", Options{}); err == nil {
+		t.Fatal("Expected the client error to be returned")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no recordings, found %d files", len(entries))
+	}
+}
+
+// TestReplayerHealthCheck verifies a missing recording directory is reported
+func TestReplayerHealthCheck(t *testing.T) {
+	if err := NewReplayer(t.TempDir() + "/missing").HealthCheck(context.Background()); err == nil {
+		t.Error("Expected error for missing recording directory")
+	}
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Chunk is one piece of a streamed response
+// The final chunk has Done set and carries the generation statistics
+type Chunk struct {
+	Text         string
+	Done         bool
+	EvalCount    int           // Tokens generated, reported on the final chunk
+	EvalDuration time.Duration // Time spent generating, reported on the final chunk
+}
+
+// StreamFunc receives chunks as they arrive
+// Returning an error aborts the request and is returned by the streaming call
+type StreamFunc func(chunk Chunk) error
+
+// defaultIdleTimeout is how long a stream may go without data before it is
+// abandoned; the length of a healthy generation is only bounded by the context
+const defaultIdleTimeout = 5 * time.Minute
+
+// stallError is returned when a stream delivers no data within the idle timeout
+// It is temporary so the request can be retried
+type stallError struct {
+	idle time.Duration
+}
+
+func (e *stallError) Error() string {
+	return fmt.Sprintf("stream stalled: no data for %v", e.idle)
+}
+
+// Temporary reports that a stalled stream may succeed if retried
+func (e *stallError) Temporary() bool {
+	return true
+}
+
+// idleReader cancels a streamed request whose body goes without data for
+// longer than its timeout
+type idleReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+// newIdleReader wraps the body of a request made with a context cancel cancels
+func newIdleReader(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleReader {
+	r := &idleReader{body: body, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		r.stalled.Store(true)
+		cancel()
+	})
+	return r
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err != nil && r.stalled.Load() {
+		return n, &stallError{idle: r.timeout}
+	}
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	return r.body.Close()
+}
@@ -0,0 +1,114 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+)
+
+// responseCache serves repeated LLM requests from the llm_cache table
+type responseCache struct {
+	model  string        // Provider and model of tasks without a model chain
+	ttl    time.Duration // Maximum age of a usable entry; zero never expires
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// CacheStats reports how many LLM calls were answered from the cache
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// EnableCache caches LLM responses in the database, keyed by a hash of the
+// model, messages and options. Entries older than ttl are evicted up front and
+// ignored afterwards; a zero ttl keeps entries forever
+func (o *Orchestrator) EnableCache(model string, ttl time.Duration) error {
+	if ttl > 0 {
+		evicted, err := o.storage.EvictCache(time.Now().Add(-ttl))
+		if err != nil {
+			return err
+		}
+		if evicted > 0 {
+			fmt.Printf("Evicted %d expired cache entries\n", evicted)
+		}
+	}
+
+	o.cache = &responseCache{model: model, ttl: ttl}
+	return nil
+}
+
+// CacheStats returns the cache statistics of the session
+// ok is false when caching is disabled
+func (o *Orchestrator) CacheStats() (stats CacheStats, ok bool) {
+	if o.cache == nil {
+		return CacheStats{}, false
+	}
+	return CacheStats{Hits: o.cache.hits.Load(), Misses: o.cache.misses.Load()}, true
+}
+
+// completeCached answers a request from the cache if possible, otherwise calls
+// the LLM and caches a successful response. Cached responses are delivered to
+// fn as a single chunk so size limits and progress still apply
+func (o *Orchestrator) completeCached(ctx context.Context, task Task, messages []llm.Message, fn llm.StreamFunc) (string, error) {
+	model := o.cache.model
+	if task.Model != "" {
+		model = task.Model
+	}
+	key := cacheKey(model, messages, task.Options)
+
+	response, found, err := o.storage.GetCachedResponse(key, o.cache.since())
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+	}
+	if found {
+		o.cache.hits.Add(1)
+		if err := fn(llm.Chunk{Text: response, Done: true}); err != nil {
+			return "", err
+		}
+		return response, nil
+	}
+
+	o.cache.misses.Add(1)
+	response, err = o.callLLM(ctx, task, messages, fn)
+	if err != nil {
+		return "", err
+	}
+
+	// A failed write only costs a future cache miss
+	if err := o.storage.PutCachedResponse(storage.CacheEntry{
+		Key:      key,
+		Model:    model,
+		Response: response,
+	}); err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+	}
+	return response, nil
+}
+
+// since returns the creation time before which entries are stale
+func (c *responseCache) since() time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-c.ttl)
+}
+
+// cacheKey hashes everything that determines an LLM response
+func cacheKey(model string, messages []llm.Message, opts llm.Options) string {
+	data, _ := json.Marshal(struct {
+		Model    string        `json:"model"`
+		Messages []llm.Message `json:"messages"`
+		Options  llm.Options   `json:"options"`
+	}{model, messages, opts})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+)
+
+// TestResponseCache verifies repeated requests are served from the database
+func TestResponseCache(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	provider := &mockLLMProvider{}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+	}
+	if err := orch.EnableCache("ollama/codellama:7b", time.Hour); err != nil {
+		t.Fatalf("EnableCache failed: %v", err)
+	}
+
+	ctx := context.Background()
+	task := Task{ID: "run_1_models", Type: "generate_models", Options: llm.DefaultOptions()}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "prompt"}}
+
+	for i := 0; i < 2; i++ {
+		code, err := orch.generateCode(ctx, task, messages)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if code != "mock generated code" {
+			t.Errorf("Expected cached code, got %q", code)
+		}
+	}
+	if provider.callCount != 1 {
+		t.Errorf("Expected 1 LLM call, got %d", provider.callCount)
+	}
+
+	// Changed options are a different request
+	task.Options = task.Options.Merge(llm.Options{Seed: llm.Int(1)})
+	if _, err := orch.generateCode(ctx, task, messages); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if provider.callCount != 2 {
+		t.Errorf("Expected changed options to miss the cache, got %d calls", provider.callCount)
+	}
+
+	stats, ok := orch.CacheStats()
+	if !ok || stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("Expected 1 hit and 2 misses, got %+v (enabled %v)", stats, ok)
+	}
+}
+
+// TestResponseCacheExpiry verifies entries older than the TTL are ignored and evicted
+func TestResponseCacheExpiry(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	store := storage.NewStorage(db)
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "prompt"}}
+	key := cacheKey("model", messages, llm.Options{})
+	if err := store.PutCachedResponse(storage.CacheEntry{Key: key, Model: "model", Response: "stale"}); err != nil {
+		t.Fatalf("PutCachedResponse failed: %v", err)
+	}
+
+	if _, found, _ := store.GetCachedResponse(key, time.Now().Add(time.Minute)); found {
+		t.Error("Expected entry older than the cutoff to be ignored")
+	}
+	if response, found, _ := store.GetCachedResponse(key, time.Time{}); !found || response != "stale" {
+		t.Errorf("Expected entry without a cutoff, got %q (found %v)", response, found)
+	}
+
+	evicted, err := store.EvictCache(time.Now().Add(time.Minute))
+	if err != nil || evicted != 1 {
+		t.Errorf("Expected 1 evicted entry, got %d (%v)", evicted, err)
+	}
+	if _, found, _ := store.GetCachedResponse(key, time.Time{}); found {
+		t.Error("Expected evicted entry to be gone")
+	}
+}
+
+// TestCacheDisabled verifies no statistics are reported without a cache
+func TestCacheDisabled(t *testing.T) {
+	orch := &Orchestrator{}
+	if _, ok := orch.CacheStats(); ok {
+		t.Error("Expected caching to be disabled by default")
+	}
+}
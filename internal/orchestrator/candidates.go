@@ -0,0 +1,247 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// phaseCandidate marks the extra completions of best-of-N generation
+const phaseCandidate = "candidate"
+
+// SetCandidates sets how many completions are generated per task by default
+// The one with the fewest diagnostics is kept; values below one mean one
+func (o *Orchestrator) SetCandidates(n int) {
+	if n < 1 {
+		n = 1
+	}
+	o.candidates = n
+}
+
+// candidateCount returns how many completions to request for a task
+func (o *Orchestrator) candidateCount(task Task) int {
+	if task.Candidates > 0 {
+		return task.Candidates
+	}
+	return max(o.candidates, 1)
+}
+
+// generateOutput produces a task's code the same way for first generations
+// and escalations: best-of-N candidates, then the in-process type check with
+// its repairs. Nothing is written
+func (o *Orchestrator) generateOutput(ctx context.Context, task Task, phase string, messages []llm.Message) (string, Task, error) {
+	code, task, err := o.generateCandidates(ctx, task, phase, messages)
+	if err != nil {
+		return "", task, err
+	}
+	code, err = o.checkOutput(ctx, task, messages, code)
+	return code, task, err
+}
+
+// generateCandidates requests several completions for a task and keeps the
+// best scored one. The first candidate is recorded under phase, uses the
+// task's own options and establishes the model; the others vary the seed and
+// raise the temperature so they actually differ. Generation stops early once
+// a candidate is free of diagnostics
+func (o *Orchestrator) generateCandidates(ctx context.Context, task Task, phase string, messages []llm.Message) (string, Task, error) {
+	code, task, err := o.generateWithFallback(ctx, task, phase, messages)
+	n := o.candidateCount(task)
+	if err != nil || n == 1 {
+		return code, task, err
+	}
+
+	// The first candidate is kept if it's the only one, but any other beats it
+	// if it can't be scored
+	best, bestScore := code, candidateScore{tier: tierUnscored}
+	if score, err := o.scoreCandidate(ctx, task, code); err != nil {
+		fmt.Printf("    Candidate 1/%d could not be scored: %v\n", n, err)
+	} else {
+		bestScore = score
+		fmt.Printf("    Candidate 1/%d: %s\n", n, bestScore)
+	}
+
+	for i := 1; i < n && !bestScore.clean(); i++ {
+		candidate := task
+		candidate.Options = task.Options.Merge(llm.Options{
+			Seed:        llm.Int(candidateSeed(task.Options, i)),
+			Temperature: llm.Float(candidateTemperature(task.Options, i)),
+		})
+
+		code, err := o.generateWithRetry(ctx, candidate, phaseCandidate, messages)
+		if err != nil {
+			// The run-wide deadline ends the search; other failures only lose a candidate
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Printf("    Candidate %d/%d failed: %v\n", i+1, n, err)
+			continue
+		}
+
+		score, err := o.scoreCandidate(ctx, task, code)
+		if err != nil {
+			fmt.Printf("    Candidate %d/%d could not be scored: %v\n", i+1, n, err)
+			continue
+		}
+		fmt.Printf("    Candidate %d/%d: %s\n", i+1, n, score)
+		if score.less(bestScore) {
+			best, bestScore = code, score
+		}
+	}
+
+	return best, task, nil
+}
+
+// candidateSeed returns the seed of the i-th extra candidate, offset from the
+// task's own seed so reruns with the same seed produce the same candidates
+func candidateSeed(opts llm.Options, i int) int {
+	base := 0
+	if opts.Seed != nil {
+		base = *opts.Seed
+	}
+	return base + i
+}
+
+// candidateTemperatureStep is how much each extra candidate raises the
+// temperature; at the low temperatures used for code a new seed alone
+// mostly reproduces the same completion
+const candidateTemperatureStep = 0.2
+
+// candidateTemperature returns the temperature of the i-th extra candidate,
+// stepping up from the task's own and capped at 1
+func candidateTemperature(opts llm.Options, i int) float64 {
+	base := *llm.DefaultOptions().Temperature
+	if opts.Temperature != nil {
+		base = *opts.Temperature
+	}
+	return min(base+candidateTemperatureStep*float64(i), max(base, 1))
+}
+
+// Candidate score tiers, best first: any syntax error ranks below any type
+// error, which ranks below any toolchain diagnostic
+const (
+	tierToolchain = iota // Diagnostics from go vet and go build, if any
+	tierType             // Type errors from the in-process check
+	tierSyntax           // Syntax errors
+	tierUnscored         // The candidate couldn't be written or checked
+)
+
+// candidateScore ranks a candidate by the tier of its worst problem, then by
+// the number of problems in that tier
+type candidateScore struct {
+	tier  int
+	count int
+}
+
+// less reports whether s is a better score than other
+func (s candidateScore) less(other candidateScore) bool {
+	if s.tier != other.tier {
+		return s.tier < other.tier
+	}
+	return s.count < other.count
+}
+
+// clean reports whether the candidate has no diagnostics at all
+func (s candidateScore) clean() bool {
+	return s.tier == tierToolchain && s.count == 0
+}
+
+func (s candidateScore) String() string {
+	switch s.tier {
+	case tierSyntax:
+		return fmt.Sprintf("%d syntax error(s)", s.count)
+	case tierType:
+		return fmt.Sprintf("%d type error(s)", s.count)
+	case tierUnscored:
+		return "unscored"
+	}
+	return fmt.Sprintf("%d diagnostic(s)", s.count)
+}
+
+// scoreCandidate scores a candidate for a task's files
+// Code that doesn't parse is scored by its syntax errors alone, and code that
+// doesn't type-check by its type errors; otherwise the validator, if any,
+// checks it in place within the rest of the project
+func (o *Orchestrator) scoreCandidate(ctx context.Context, task Task, code string) (candidateScore, error) {
+	files := splitFiles(code, task.OutputPath)
+
+	syntax := 0
+	for _, file := range files {
+		syntax += syntaxErrors(file.Path, file.Content)
+	}
+	if syntax > 0 {
+		return candidateScore{tier: tierSyntax, count: syntax}, nil
+	}
+	if o.checker != nil {
+		if n := len(o.checkDiagnostics(task, code)); n > 0 {
+			return candidateScore{tier: tierType, count: n}, nil
+		}
+	}
+	if o.validator == nil {
+		return candidateScore{tier: tierToolchain}, nil
+	}
+
+	// Candidates share the work directory, so only one is built at a time
+	o.candidateMu.Lock()
+	defer o.candidateMu.Unlock()
+
+	// Files the candidate overwrites are restored and files only it has are
+	// removed again, so a losing candidate can't clobber other tasks' output
+	// or break later builds
+	unscored := candidateScore{tier: tierUnscored}
+	if err := o.checkOutputPaths(task, files); err != nil {
+		return unscored, err
+	}
+	for _, file := range files {
+		outputPath := filepath.Join(o.workDir, filepath.FromSlash(file.Path))
+		original, err := os.ReadFile(outputPath)
+		switch {
+		case err == nil:
+			defer os.WriteFile(outputPath, original, 0644)
+		case errors.Is(err, fs.ErrNotExist):
+			defer os.Remove(outputPath)
+		default:
+			return unscored, fmt.Errorf("failed to read %s: %w", outputPath, err)
+		}
+	}
+
+	if err := o.writeOutput(task, code); err != nil {
+		return unscored, fmt.Errorf("failed to write candidate: %w", err)
+	}
+
+	paths := filePaths(files)
+	count := 0
+	for _, d := range o.collectDiagnostics(ctx) {
+		if slices.Contains(paths, d.File) {
+			count++
+		}
+	}
+	return candidateScore{tier: tierToolchain, count: count}, nil
+}
+
+// syntaxErrors parses Go source and returns the number of syntax errors
+// Files that aren't Go source are not checked
+func syntaxErrors(path, code string) int {
+	if filepath.Ext(path) != ".go" {
+		return 0
+	}
+
+	_, err := parser.ParseFile(token.NewFileSet(), path, code, parser.AllErrors)
+	if err == nil {
+		return 0
+	}
+
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		return len(list)
+	}
+	return 1
+}
@@ -0,0 +1,254 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+)
+
+// seedRecordingProvider replies with scripted code and records the seed and
+// temperature of every call
+type seedRecordingProvider struct {
+	mockLLMProvider
+	replies []string
+	seeds   []int
+	temps   []float64
+}
+
+func (m *seedRecordingProvider) Complete(ctx context.Context, prompt string, opts llm.Options) (string, error) {
+	seed := -1
+	if opts.Seed != nil {
+		seed = *opts.Seed
+	}
+	temp := -1.0
+	if opts.Temperature != nil {
+		temp = *opts.Temperature
+	}
+	m.seeds = append(m.seeds, seed)
+	m.temps = append(m.temps, temp)
+	return m.replies[len(m.seeds)-1], nil
+}
+
+// TestGenerateCandidatesSyntax verifies candidates are ranked by syntax errors and the search stops at a clean one
+func TestGenerateCandidatesSyntax(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	provider := &seedRecordingProvider{replies: []string{
+		"package models\n\nfunc (",
+		"package models\n\ntype Todo struct{}",
+		"package models\n\nfunc (",
+	}}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+	}
+	orch.SetCandidates(3)
+
+	task := Task{ID: "run_1_models", OutputPath: "models.go", Options: llm.Options{Seed: llm.Int(10)}}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusRunning)})
+	code, _, err := orch.generateCandidates(context.Background(), task, phaseGenerate, []llm.Message{{Role: llm.RoleUser, Content: "prompt"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if code != "package models\n\ntype Todo struct{}" {
+		t.Errorf("Expected the candidate that parses, got %q", code)
+	}
+
+	// The first candidate keeps the task's seed; the search ends at the clean second one
+	if fmt.Sprint(provider.seeds) != "[10 11]" {
+		t.Errorf("Expected seeds [10 11], got %v", provider.seeds)
+	}
+}
+
+// TestGenerateCandidatesValidator verifies candidates are ranked by validator errors in their own file
+func TestGenerateCandidatesValidator(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	provider := &seedRecordingProvider{replies: []string{
+		"package models\n\n// first",
+		"package models\n\n// second",
+		"package models\n\n// third",
+	}}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		workDir: t.TempDir(),
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+		validator: &fakeValidator{rounds: [][]validator.ValidationResult{
+			{{Tool: "go build", Output: "models.go:3:1: undefined: a\nmodels.go:4:1: undefined: b"}},
+			{{Tool: "go build", Output: "models.go:3:1: undefined: a\nother.go:1:1: undefined: c\nother.go:2:1: undefined: d"}},
+			{{Tool: "go build", Output: "models.go:3:1: undefined: a\nmodels.go:4:1: undefined: b"}},
+		}},
+	}
+	orch.SetCandidates(3)
+
+	task := Task{ID: "run_1_models", OutputPath: "models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusRunning)})
+	code, _, err := orch.generateCandidates(context.Background(), task, phaseGenerate, []llm.Message{{Role: llm.RoleUser, Content: "prompt"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if code != "package models\n\n// second" {
+		t.Errorf("Expected the second candidate, got %q", code)
+	}
+	if fmt.Sprint(provider.seeds) != "[-1 1 2]" {
+		t.Errorf("Expected unseeded first candidate then seeds 1 and 2, got %v", provider.seeds)
+	}
+
+	// Extra candidates step up from the default temperature
+	if fmt.Sprintf("%.1f", provider.temps) != "[-1.0 0.4 0.6]" {
+		t.Errorf("Expected default first candidate then rising temperatures, got %v", provider.temps)
+	}
+}
+
+// TestGenerateCandidatesTiers verifies any syntax error ranks below any type
+// error, however many there are
+func TestGenerateCandidatesTiers(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "go.mod"), []byte("module todo-api\n"), 0644)
+
+	provider := &seedRecordingProvider{replies: []string{
+		"package models\n\nfunc (",
+		"package models\n\nvar a int = \"a\"\nvar b int = \"b\"\nvar c int = \"c\"",
+		"package models\n\nfunc (",
+	}}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		workDir: workDir,
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+		checker: validator.NewChecker(workDir),
+	}
+	orch.SetCandidates(3)
+
+	task := Task{ID: "run_1_models", OutputPath: "internal/models/models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusRunning)})
+	code, _, err := orch.generateCandidates(context.Background(), task, phaseGenerate, []llm.Message{{Role: llm.RoleUser, Content: "prompt"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if code != provider.replies[1] {
+		t.Errorf("Expected the candidate with only type errors, got %q", code)
+	}
+}
+
+// TestCandidateScore verifies scores are ordered by tier before count
+func TestCandidateScore(t *testing.T) {
+	ordered := []candidateScore{
+		{tier: tierToolchain},
+		{tier: tierToolchain, count: 5},
+		{tier: tierType, count: 1},
+		{tier: tierType, count: 9},
+		{tier: tierSyntax, count: 1},
+		{tier: tierUnscored},
+	}
+	for i := 1; i < len(ordered); i++ {
+		if !ordered[i-1].less(ordered[i]) || ordered[i].less(ordered[i-1]) {
+			t.Errorf("Expected %v to rank above %v", ordered[i-1], ordered[i])
+		}
+	}
+	if !ordered[0].clean() || ordered[1].clean() {
+		t.Error("Only a toolchain score without diagnostics is clean")
+	}
+}
+
+// TestCandidateTemperature verifies extra candidates raise the temperature up to 1
+func TestCandidateTemperature(t *testing.T) {
+	tests := []struct {
+		temp *float64
+		i    int
+		want float64
+	}{
+		{nil, 1, 0.4},
+		{llm.Float(0.5), 1, 0.7},
+		{llm.Float(0.9), 2, 1},
+		{llm.Float(1.2), 1, 1.2},
+	}
+	for _, tt := range tests {
+		got := candidateTemperature(llm.Options{Temperature: tt.temp}, tt.i)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("candidateTemperature(%v, %d) = %v, want %v", tt.temp, tt.i, got, tt.want)
+		}
+	}
+}
+
+// TestGenerateCandidatesUnwritable verifies a candidate that can't be written
+// loses to any other, and losing candidates leave other files as they were
+func TestGenerateCandidatesUnwritable(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	shared := filepath.Join(workDir, "shared.go")
+	if err := os.WriteFile(shared, []byte("package models\n\n// other task\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &seedRecordingProvider{replies: []string{
+		"// file: shared.go/models.go\npackage models",
+		"package models\n\n// second\n\n// file: shared.go\npackage models\n\n// overwritten",
+		"package models\n\n// third",
+	}}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		workDir: workDir,
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+		validator: &fakeValidator{rounds: [][]validator.ValidationResult{
+			{{Tool: "go build", Output: "models.go:3:1: undefined: a\nmodels.go:4:1: undefined: b"}},
+			{{Tool: "go build", Output: "models.go:3:1: undefined: a"}},
+		}},
+	}
+	orch.SetCandidates(3)
+
+	task := Task{ID: "run_1_models", OutputPath: "models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusRunning)})
+	code, _, err := orch.generateCandidates(context.Background(), task, phaseGenerate, []llm.Message{{Role: llm.RoleUser, Content: "prompt"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if code != "package models\n\n// third" {
+		t.Errorf("Expected the third candidate, got %q", code)
+	}
+
+	content, err := os.ReadFile(shared)
+	if err != nil || string(content) != "package models\n\n// other task\n" {
+		t.Errorf("Expected shared.go restored, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "models.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected candidate files removed, got %v", err)
+	}
+}
+
+// TestSyntaxErrors verifies in-process parsing of candidates
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		code    string
+		invalid bool
+	}{
+		{"models.go", "package models\n\ntype Todo struct{}\n", false},
+		{"models.go", "package models\n\nfunc (\n", true},
+		{"models.go", "not go at all", true},
+		{"README.md", "not go at all", false},
+	}
+
+	for _, tt := range tests {
+		if got := syntaxErrors(tt.path, tt.code); (got > 0) != tt.invalid {
+			t.Errorf("syntaxErrors(%q, %q) = %d, want invalid %v", tt.path, tt.code, got, tt.invalid)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/validator"
+)
+
+// SourceChecker parses and type-checks code in-process, before it is written
+// Implemented by validator.Checker; tests substitute fakes
+type SourceChecker interface {
+	Check(overlay map[string]string) []validator.Diagnostic
+}
+
+// SetChecker enables in-process checking of every task's output before it is saved
+func (o *Orchestrator) SetChecker(c SourceChecker) {
+	o.checker = c
+}
+
+// checkOutput checks a task's code before it is written and asks the LLM to
+// fix errors in its files, up to MaxRetries times. Code that still has errors
+// is left to the toolchain repair loop if there is one, and rejected otherwise
+func (o *Orchestrator) checkOutput(ctx context.Context, task Task, messages []llm.Message, code string) (string, error) {
+	if o.checker == nil {
+		return code, nil
+	}
+
+	conversation := messages
+	for round := 0; ; round++ {
+		diags := o.checkDiagnostics(task, code)
+		if len(diags) == 0 {
+			return code, nil
+		}
+
+		if round >= o.limits.MaxRetries {
+			if o.validator != nil {
+				fmt.Printf("    %d error(s) remain in %s, leaving them to the repair loop\n", len(diags), task.OutputPath)
+				return code, nil
+			}
+			d := diags[0]
+			return "", fmt.Errorf("rejected output with %d error(s), first at %s:%d:%d: %s", len(diags), d.File, d.Line, d.Column, d.Message)
+		}
+
+		files := splitFiles(code, task.OutputPath)
+		conversation = append(conversation,
+			llm.Message{Role: llm.RoleAssistant, Content: code},
+			llm.Message{Role: llm.RoleUser, Content: buildRepairPrompt(filePaths(files), diags)},
+		)
+
+		fmt.Printf("  → Fixing %s (%d error(s) found by type checking)...\n", task.OutputPath, len(diags))
+		reply, err := o.generateWithRetry(ctx, task, phaseRepair, conversation)
+		if err != nil {
+			return "", err
+		}
+		code = joinFiles(mergeFiles(files, splitFiles(reply, task.OutputPath)), task.OutputPath)
+	}
+}
+
+// checkDiagnostics type-checks code as a task's files within the rest of the
+// work directory and returns the errors found in those files
+func (o *Orchestrator) checkDiagnostics(task Task, code string) []validator.Diagnostic {
+	files := splitFiles(code, task.OutputPath)
+	overlay := make(map[string]string, len(files))
+	for _, file := range files {
+		overlay[file.Path] = file.Content
+	}
+
+	paths := filePaths(files)
+	var diags []validator.Diagnostic
+	for _, d := range o.checker.Check(overlay) {
+		if slices.Contains(paths, d.File) {
+			d.TaskID = task.ID
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+)
+
+// TestCheckOutputRepairs verifies type errors are fixed before the file is written
+func TestCheckOutputRepairs(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "go.mod"), []byte("module todo-api\n"), 0644)
+
+	provider := &seedRecordingProvider{replies: []string{
+		"package models\n\nfunc Count() int {\n\treturn 1\n}",
+	}}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		workDir: workDir,
+		limits:  SafetyLimits{MaxRetries: 2, MaxOutputSize: 1024},
+		checker: validator.NewChecker(workDir),
+	}
+
+	task := Task{ID: "run_1_models", OutputPath: "internal/models/count.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusRunning)})
+
+	broken := "package models\n\nfunc Count() int {\n\treturn \"one\"\n}"
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "prompt"}}
+	code, err := orch.checkOutput(context.Background(), task, messages, broken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(code, "return 1") {
+		t.Errorf("Expected the fixed code, got %q", code)
+	}
+	if len(provider.seeds) != 1 {
+		t.Errorf("Expected 1 repair call, got %d", len(provider.seeds))
+	}
+
+	// Nothing is written by the check itself
+	if _, err := os.Stat(filepath.Join(workDir, "internal", "models", "count.go")); err == nil {
+		t.Error("Checked code should not be written")
+	}
+}
+
+// TestCheckOutputRejects verifies code that stays broken is rejected without a repair loop
+func TestCheckOutputRejects(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "go.mod"), []byte("module todo-api\n"), 0644)
+
+	broken := "package models\n\nvar count int = \"one\""
+	provider := &seedRecordingProvider{replies: []string{broken, broken}}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		workDir: workDir,
+		limits:  SafetyLimits{MaxRetries: 1, MaxOutputSize: 1024},
+		checker: validator.NewChecker(workDir),
+	}
+
+	task := Task{ID: "run_1_models", OutputPath: "models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusRunning)})
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "prompt"}}
+	_, err := orch.checkOutput(context.Background(), task, messages, broken)
+	if err == nil || !strings.Contains(err.Error(), "models.go:3:") {
+		t.Fatalf("Expected rejection pointing at models.go:3, got %v", err)
+	}
+
+	// With the toolchain repair loop enabled the code is kept for it instead
+	orch.validator = &fakeValidator{rounds: [][]validator.ValidationResult{{{Tool: "go build", Success: true}}}}
+	code, err := orch.checkOutput(context.Background(), task, messages, broken)
+	if err != nil || code != broken {
+		t.Errorf("Expected the code kept for the repair loop, got %q, %v", code, err)
+	}
+}
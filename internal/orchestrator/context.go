@@ -0,0 +1,143 @@
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// Context modes control how dependency outputs are shown to downstream prompts
+const (
+	ContextAPI  = "api"  // Exported declarations and signatures only (default)
+	ContextFull = "full" // Complete source of every dependency
+	ContextNone = "none" // No dependency context
+)
+
+// buildPrompt loads a task's prompt as chat messages: the prompt file's system
+// section, if any, then the user prompt followed by the code its dependencies produced
+func (o *Orchestrator) buildPrompt(task Task) ([]llm.Message, error) {
+	system, user, err := o.loadPrompt(task)
+	if err != nil {
+		return nil, err
+	}
+
+	depContext, err := o.dependencyContext(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []llm.Message
+	if system != "" {
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: system})
+	}
+	return append(messages, llm.Message{Role: llm.RoleUser, Content: user + depContext}), nil
+}
+
+// dependencyContext renders the outputs of a task's dependencies for its prompt
+// Outputs are read from storage so resumed runs see the same context
+func (o *Orchestrator) dependencyContext(task Task) (string, error) {
+	if len(task.DependsOn) == 0 || task.ContextMode == ContextNone {
+		return "", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nThe following code has already been generated for this project.")
+	b.WriteString(" Use these exact types, functions and method signatures and import them")
+	b.WriteString(" by the import paths shown. Do not redefine them.\n")
+
+	for _, depID := range task.DependsOn {
+		dep, ok := o.tasks[depID]
+		if !ok {
+			return "", fmt.Errorf("unknown dependency %s for task %s", depID, task.ID)
+		}
+
+		stored, err := o.storage.GetTask(depID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load output of %s: %w", depID, err)
+		}
+
+		// Each file of a multi-file output is shown with its own import path
+		for _, file := range splitFiles(stored.Output, dep.OutputPath) {
+			code := file.Content
+			if task.ContextMode != ContextFull {
+				code = extractAPISurface(code)
+			}
+
+			fmt.Fprintf(&b, "\n--- %s (import path: %s) ---\n", file.Path, o.importPath(file.Path))
+			b.WriteString(strings.TrimSpace(code))
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String(), nil
+}
+
+// importPath returns the Go import path of the package containing a generated file
+func (o *Orchestrator) importPath(outputPath string) string {
+	dir := path.Dir(path.Clean(outputPath))
+	if dir == "." {
+		return o.modulePath()
+	}
+	return o.modulePath() + "/" + dir
+}
+
+// extractFilesAPI reduces every file of a task output to its API surface
+func extractFilesAPI(output, defaultPath string) string {
+	files := splitFiles(output, defaultPath)
+	for i := range files {
+		files[i].Content = strings.TrimSpace(extractAPISurface(files[i].Content))
+	}
+	return joinFiles(files, defaultPath)
+}
+
+// extractAPISurface reduces Go source to its package clause and declarations
+// Function bodies, imports and non-doc comments are dropped. Source that does
+// not parse is returned unchanged so the model still sees something useful
+func extractAPISurface(src string) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	var decls []ast.Decl
+	var docs []*ast.CommentGroup
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			if d.Doc != nil {
+				docs = append(docs, d.Doc)
+			}
+		case *ast.FuncDecl:
+			d.Body = nil
+			if d.Doc != nil {
+				docs = append(docs, d.Doc)
+			}
+		}
+		decls = append(decls, decl)
+	}
+
+	file.Decls = decls
+	file.Imports = nil
+	if file.Doc == nil {
+		file.Comments = docs
+	} else {
+		file.Comments = append([]*ast.CommentGroup{file.Doc}, docs...)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return src
+	}
+	return buf.String()
+}
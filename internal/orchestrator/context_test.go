@@ -0,0 +1,138 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/storage"
+)
+
+const modelsSource = `package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Todo is a single todo item
+type Todo struct {
+	ID        int64     ` + "`json:\"id\"`" + `
+	Title     string    ` + "`json:\"title\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+
+// Validate checks required fields
+func (t *Todo) Validate() error {
+	// Titles are mandatory
+	if t.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
+`
+
+// TestExtractAPISurface verifies bodies and imports are stripped from context
+func TestExtractAPISurface(t *testing.T) {
+	surface := extractAPISurface(modelsSource)
+
+	for _, want := range []string{
+		"package models",
+		"type Todo struct",
+		"CreatedAt time.Time",
+		"// Validate checks required fields",
+		"func (t *Todo) Validate() error",
+	} {
+		if !strings.Contains(surface, want) {
+			t.Errorf("API surface missing %q:\n%s", want, surface)
+		}
+	}
+
+	for _, unwanted := range []string{`"errors"`, "title is required", "Titles are mandatory"} {
+		if strings.Contains(surface, unwanted) {
+			t.Errorf("API surface should not contain %q:\n%s", unwanted, surface)
+		}
+	}
+
+	// Unparseable code is passed through unchanged
+	broken := "package models\n\nfunc broken( {"
+	if got := extractAPISurface(broken); got != broken {
+		t.Errorf("Expected broken source unchanged, got %q", got)
+	}
+}
+
+// TestDependencyContext verifies dependency outputs are injected into prompts
+func TestDependencyContext(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "handlers.txt"), []byte("handlers prompt"), 0644)
+
+	var captured string
+	mockLLM := &mockLLMProvider{
+		completeFunc: func(ctx context.Context, prompt string) (string, error) {
+			captured = prompt
+			return "package handlers", nil
+		},
+	}
+
+	orch := &Orchestrator{
+		llm:         mockLLM,
+		storage:     storage.NewStorage(db),
+		workDir:     workDir,
+		promptsPath: workDir,
+		module:      "example.com/todo",
+		limits:      SafetyLimits{MaxOutputSize: 1024},
+	}
+
+	models := Task{ID: "run_1_models", OutputPath: "internal/models/todo.go"}
+	handlers := Task{
+		ID:          "run_1_handlers",
+		PromptFile:  "handlers.txt",
+		OutputPath:  "internal/handlers/todo.go",
+		DependsOn:   []string{models.ID},
+		ContextMode: ContextAPI,
+	}
+	orch.tasks = map[string]Task{models.ID: models, handlers.ID: handlers}
+
+	orch.storage.CreateTask(storage.Task{ID: models.ID, Type: "models", Status: string(StatusComplete)})
+	orch.storage.UpdateTaskOutput(models.ID, modelsSource)
+	orch.storage.CreateTask(storage.Task{ID: handlers.ID, Type: "handlers", Status: string(StatusPending)})
+
+	if err := orch.executeTask(context.Background(), handlers); err != nil {
+		t.Fatalf("Failed to execute task: %v", err)
+	}
+
+	for _, want := range []string{
+		"handlers prompt",
+		"--- internal/models/todo.go (import path: example.com/todo/internal/models) ---",
+		"func (t *Todo) Validate() error",
+	} {
+		if !strings.Contains(captured, want) {
+			t.Errorf("Prompt missing %q:\n%s", want, captured)
+		}
+	}
+	if strings.Contains(captured, "title is required") {
+		t.Error("API context should not include function bodies")
+	}
+
+	// Full context includes the complete source
+	handlers.ContextMode = ContextFull
+	full, err := orch.dependencyContext(handlers)
+	if err != nil {
+		t.Fatalf("Failed to build full context: %v", err)
+	}
+	if !strings.Contains(full, "title is required") {
+		t.Error("Full context should include function bodies")
+	}
+
+	// Context can be disabled per task
+	handlers.ContextMode = ContextNone
+	none, _ := orch.dependencyContext(handlers)
+	if none != "" {
+		t.Errorf("Expected no context, got %q", none)
+	}
+}
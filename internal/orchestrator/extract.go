@@ -0,0 +1,139 @@
+package orchestrator
+
+import (
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// packageClausePattern matches the package clause that starts a Go file
+var packageClausePattern = regexp.MustCompile(`(?m)^package\s+\w+\s*$`)
+
+// extractCode pulls the code out of an LLM response
+// LLMs often surround code with prose or wrap it in ```go blocks despite
+// instructions. Fenced blocks are used wherever they appear, preferring blocks
+// tagged go; without fences the code starts at the first package clause (or
+// file header) and explanations after the last complete declaration are dropped
+func extractCode(raw string) string {
+	raw = strings.TrimSpace(raw)
+
+	if fences := findFences(raw); len(fences) > 0 {
+		return strings.TrimSpace(joinFences(codeFences(fences)))
+	}
+
+	// A response wrapped in inline backticks
+	if strings.HasPrefix(raw, "`") && strings.HasSuffix(raw, "`") {
+		raw = strings.TrimSpace(strings.Trim(raw, "`"))
+	}
+
+	loc := packageClausePattern.FindStringIndex(raw)
+	if loc == nil {
+		return raw
+	}
+
+	// Multi-file answers start with the header naming the first file, and
+	// each file is trimmed on its own so one file's end isn't taken for prose
+	offset, prev := 0, ""
+	for _, line := range strings.SplitAfter(raw[:loc[0]], "\n") {
+		if fileHeader(strings.TrimRight(line, "\n"), prev) != "" {
+			files := splitFiles(raw[offset:], "")
+			for i := range files {
+				files[i].Content = trimTrailingProse(files[i].Content)
+			}
+			return joinFiles(files, "")
+		}
+		offset += len(line)
+		prev = line
+	}
+	return trimTrailingProse(raw[loc[0]:])
+}
+
+// codeFences picks the blocks holding the answer's code
+// Blocks tagged go win over untagged ones, which win over other languages.
+// Among those, blocks with a package clause are preferred so usage snippets
+// and shell commands shown alongside a file are left out
+func codeFences(fences []fence) []fence {
+	var tagged, untagged []fence
+	for _, f := range fences {
+		switch f.language() {
+		case "go":
+			tagged = append(tagged, f)
+		case "":
+			untagged = append(untagged, f)
+		}
+	}
+
+	chosen := fences
+	if len(tagged) > 0 {
+		chosen = tagged
+	} else if len(untagged) > 0 {
+		chosen = untagged
+	}
+
+	var files []fence
+	for _, f := range chosen {
+		if packageClausePattern.MatchString(f.Body) {
+			files = append(files, f)
+		}
+	}
+	if len(files) > 0 {
+		return files
+	}
+	return chosen
+}
+
+// joinFences concatenates the bodies of fenced blocks
+func joinFences(fences []fence) string {
+	bodies := make([]string, len(fences))
+	for i, f := range fences {
+		bodies[i] = strings.TrimSpace(f.Body)
+	}
+	return strings.Join(bodies, "\n\n")
+}
+
+// language returns the language a fenced block is tagged with, lowercased
+// "golang" counts as go; an info string holding only a path is untagged
+func (f fence) language() string {
+	fields := strings.Fields(strings.ReplaceAll(f.Info, ":", " "))
+	if len(fields) == 0 || asPath(fields[0]) != "" || strings.Contains(fields[0], "=") {
+		return ""
+	}
+
+	lang := strings.ToLower(fields[0])
+	if lang == "golang" {
+		return "go"
+	}
+	return lang
+}
+
+// trimTrailingProse drops text after the code of a Go file
+// If the file doesn't parse, it is cut after the last top-level closing brace
+// or parenthesis that leaves a parseable file. Text that never parses is
+// returned as-is so the validator can report the real errors
+func trimTrailingProse(code string) string {
+	code = strings.TrimSpace(code)
+	if parses(code) {
+		return code
+	}
+
+	lines := strings.Split(code, "\n")
+	for i := len(lines) - 1; i > 0; i-- {
+		line := strings.TrimRight(lines[i], " \t")
+		if line != ")" && !strings.HasPrefix(line, "}") {
+			continue
+		}
+
+		candidate := strings.Join(lines[:i+1], "\n")
+		if parses(candidate) {
+			return candidate
+		}
+	}
+	return code
+}
+
+// parses reports whether code is a syntactically valid Go file
+func parses(code string) bool {
+	_, err := parser.ParseFile(token.NewFileSet(), "", code, parser.SkipObjectResolution)
+	return err == nil
+}
@@ -0,0 +1,85 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// TestExtractCodeCorpus checks extraction against model responses
+// Each testdata/responses/<name>.json is a recording saved by -record, and
+// each <name>.txt a raw response reproducing a style; <name>.want holds the
+// code that should be extracted from it
+func TestExtractCodeCorpus(t *testing.T) {
+	var inputs []string
+	for _, pattern := range []string{"*.json", "*.txt"} {
+		matches, err := filepath.Glob(filepath.Join("testdata", "responses", pattern))
+		if err != nil {
+			t.Fatalf("Failed to list fixtures: %v", err)
+		}
+		inputs = append(inputs, matches...)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("No response fixtures found")
+	}
+
+	for _, input := range inputs {
+		ext := filepath.Ext(input)
+		name := strings.TrimSuffix(filepath.Base(input), ext)
+		t.Run(name, func(t *testing.T) {
+			raw, err := readResponse(input)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			want, err := os.ReadFile(strings.TrimSuffix(input, ext) + ".want")
+			if err != nil {
+				t.Fatalf("Failed to read expected output: %v", err)
+			}
+
+			got := extractCode(raw)
+			if got != strings.TrimSpace(string(want)) {
+				t.Errorf("Extracted code mismatch\n--- got ---\n%s\n--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
+
+// readResponse reads the model response of a fixture: the response field of
+// a recording, or the whole file otherwise
+func readResponse(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Ext(path) != ".json" {
+		return string(data), nil
+	}
+
+	var rec llm.Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return "", err
+	}
+	if rec.Response == "" {
+		return "", fmt.Errorf("recording %s has no response", path)
+	}
+	return rec.Response, nil
+}
+
+// TestTrimTrailingProse verifies only text after the last declaration is dropped
+func TestTrimTrailingProse(t *testing.T) {
+	code := "package main\n\nfunc main() {\n}\n\nThat's the whole program."
+	if got := trimTrailingProse(code); got != "package main\n\nfunc main() {\n}" {
+		t.Errorf("Expected trailing prose removed, got %q", got)
+	}
+
+	// Code with a genuine syntax error is left for the validator to report
+	broken := "package main\n\nfunc main() {\n\tx :=\n}"
+	if got := trimTrailingProse(broken); got != broken {
+		t.Errorf("Expected broken code unchanged, got %q", got)
+	}
+}
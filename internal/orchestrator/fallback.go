@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// ModelResolver returns the provider serving a model reference
+// References are opaque to the orchestrator; the caller decides their format
+type ModelResolver func(model string) (LLMProvider, error)
+
+// SetModels sets the default model chain and how its references are resolved
+// Tasks start with the first model and fall back to the next one after
+// repeated generation or validation failures. Pipeline and task "models" lists
+// replace the default chain
+func (o *Orchestrator) SetModels(models []string, resolve ModelResolver) {
+	o.models = models
+	o.resolveModel = resolve
+	o.providers = make(map[string]LLMProvider)
+}
+
+// modelChain returns the ordered models a task may be generated with
+// An empty chain means the orchestrator's own provider is used
+func (o *Orchestrator) modelChain(task Task) []string {
+	if len(task.Models) > 0 {
+		return task.Models
+	}
+	return o.models
+}
+
+// nextModel returns the model after the task's current one in its chain
+// A task whose model is not in the chain starts at the beginning
+func (o *Orchestrator) nextModel(task Task) (string, bool) {
+	chain := o.modelChain(task)
+	next := slices.Index(chain, task.Model) + 1
+	if next >= len(chain) {
+		return "", false
+	}
+	return chain[next], true
+}
+
+// providerFor returns the provider for a model, resolving each model only once
+func (o *Orchestrator) providerFor(model string) (LLMProvider, error) {
+	if model == "" {
+		return o.llm, nil
+	}
+	if o.resolveModel == nil {
+		return nil, fmt.Errorf("no provider configured for model %s", model)
+	}
+
+	o.providersMu.Lock()
+	defer o.providersMu.Unlock()
+
+	if provider, ok := o.providers[model]; ok {
+		return provider, nil
+	}
+	provider, err := o.resolveModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("model %s: %w", model, err)
+	}
+	o.providers[model] = provider
+	return provider, nil
+}
+
+// generateWithFallback generates code with the task's model, moving down its
+// model chain whenever a model exhausts its retries. The returned task carries
+// the model that produced the code, which is also stored with the task
+func (o *Orchestrator) generateWithFallback(ctx context.Context, task Task, phase string, messages []llm.Message) (string, Task, error) {
+	if task.Model == "" {
+		if first, ok := o.nextModel(task); ok {
+			task.Model = first
+		}
+	}
+
+	for {
+		code, err := o.generateWithRetry(ctx, task, phase, messages)
+		if err == nil {
+			if task.Model != "" {
+				if err := o.storage.UpdateTaskModel(task.ID, task.Model); err != nil {
+					fmt.Printf("Failed to record model of task %s: %v\n", task.ID, err)
+				}
+			}
+			return code, task, nil
+		}
+
+		// The run-wide deadline applies to every model alike
+		next, ok := o.nextModel(task)
+		if !ok || ctx.Err() != nil {
+			return "", task, err
+		}
+		fmt.Printf("    %s failed for %s: %v (falling back to %s)\n", task.Model, task.Type, err, next)
+		task.Model = next
+	}
+}
+
+// escalateTask regenerates a task from scratch with the next model in its
+// chain after its code kept failing validation, through the same candidate
+// selection and type check as its first generation. Returns false if the
+// task is already on its last model
+func (o *Orchestrator) escalateTask(ctx context.Context, task Task, reason string) (bool, error) {
+	next, ok := o.nextModel(task)
+	if !ok {
+		return false, nil
+	}
+
+	fmt.Printf("  → Escalating %s from %s to %s: %s\n", task.OutputPath, task.Model, next, reason)
+	task.Model = next
+
+	messages, err := o.buildPrompt(task)
+	if err != nil {
+		return false, fmt.Errorf("failed to load prompt: %w", err)
+	}
+
+	code, task, err := o.generateOutput(ctx, task, phaseEscalate, messages)
+	if err != nil {
+		return false, err
+	}
+
+	if err := o.saveOutput(task, code); err != nil {
+		return false, fmt.Errorf("failed to save output: %w", err)
+	}
+	if err := o.storage.UpdateTaskOutput(task.ID, code); err != nil {
+		return false, fmt.Errorf("failed to save task output: %w", err)
+	}
+	return true, nil
+}
+
+// withStoredModel sets the model that produced a task's output, as recorded
+// in the database, on tasks built from the pipeline
+func (o *Orchestrator) withStoredModel(task Task) Task {
+	if task.Model != "" {
+		return task
+	}
+	if stored, err := o.storage.GetTask(task.ID); err == nil {
+		task.Model = stored.Model
+	}
+	return task
+}
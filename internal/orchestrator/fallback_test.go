@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+)
+
+// newFallbackOrchestrator creates an orchestrator whose models are served by the given providers
+func newFallbackOrchestrator(t *testing.T, providers map[string]*mockLLMProvider, chain ...string) *Orchestrator {
+	t.Helper()
+
+	db, cleanup := createTestDB(t)
+	t.Cleanup(cleanup)
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "models.txt"), []byte("models prompt"), 0644)
+
+	orch := &Orchestrator{
+		storage:     storage.NewStorage(db),
+		workDir:     workDir,
+		promptsPath: workDir,
+		limits:      SafetyLimits{MaxRetries: 1, MaxOutputSize: 1024},
+	}
+	orch.SetModels(chain, func(model string) (LLMProvider, error) {
+		provider, ok := providers[model]
+		if !ok {
+			return nil, fmt.Errorf("unknown model")
+		}
+		return provider, nil
+	})
+	return orch
+}
+
+// TestGenerateWithFallback verifies failing models are skipped and the accepted model recorded
+func TestGenerateWithFallback(t *testing.T) {
+	small := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		return "", errors.New("model crashed")
+	}}
+	large := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		return "package models", nil
+	}}
+	orch := newFallbackOrchestrator(t, map[string]*mockLLMProvider{"small": small, "large": large},
+		"missing", "small", "large")
+
+	task := Task{ID: "run_1_models", Type: "generate_models", PromptFile: "models.txt", OutputPath: "models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusPending)})
+
+	if err := orch.executeTask(context.Background(), task); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Non-retryable failures move on without retrying the same model
+	if small.callCount != 1 || large.callCount != 1 {
+		t.Errorf("Expected one call per available model, got small=%d large=%d", small.callCount, large.callCount)
+	}
+
+	stored, _ := orch.storage.GetTask(task.ID)
+	if stored.Model != "large" || stored.Status != string(StatusComplete) {
+		t.Errorf("Expected task completed by large, got %s (%s)", stored.Model, stored.Status)
+	}
+
+	files, _ := orch.storage.GetGeneratedFiles(task.ID)
+	if len(files) != 1 || files[0].Model != "large" {
+		t.Errorf("Expected file recorded as produced by large, got %+v", files)
+	}
+
+	attempts, _ := orch.storage.GetAttempts(task.ID)
+	var models []string
+	for _, attempt := range attempts {
+		models = append(models, attempt.Model)
+	}
+	if strings.Join(models, ",") != "missing,small,large" {
+		t.Errorf("Expected an attempt per model, got %v", models)
+	}
+}
+
+// TestGenerateWithFallbackExhausted verifies the last model's error is returned
+func TestGenerateWithFallbackExhausted(t *testing.T) {
+	failing := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		return "", errors.New("model crashed")
+	}}
+	orch := newFallbackOrchestrator(t, map[string]*mockLLMProvider{"a": failing, "b": failing}, "a", "b")
+
+	task := Task{ID: "run_1_models", Type: "generate_models"}
+	_, task, err := orch.generateWithFallback(context.Background(), task, phaseGenerate, nil)
+	if err == nil || !strings.Contains(err.Error(), "model crashed") {
+		t.Fatalf("Expected the last model's error, got %v", err)
+	}
+	if task.Model != "b" || failing.callCount != 2 {
+		t.Errorf("Expected both models tried, ended on %s after %d calls", task.Model, failing.callCount)
+	}
+}
+
+// TestRepairEscalatesModel verifies code that keeps failing validation is regenerated with the next model
+func TestRepairEscalatesModel(t *testing.T) {
+	small := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		return "package models\n\nbroken", nil
+	}}
+	var escalated string
+	large := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		escalated = prompt
+		return "package models\n\n// fixed", nil
+	}}
+	orch := newFallbackOrchestrator(t, map[string]*mockLLMProvider{"small": small, "large": large}, "small", "large")
+	orch.validator = &fakeValidator{rounds: [][]validator.ValidationResult{
+		{{Tool: "go build", Output: "models.go:3:1: syntax error"}},
+		{{Tool: "go build", Output: "models.go:3:1: syntax error"}},
+		{{Tool: "go build", Success: true}},
+	}}
+
+	task := Task{ID: "run_1_models", PromptFile: "models.txt", OutputPath: "models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusComplete)})
+	orch.storage.UpdateTaskModel(task.ID, "small")
+	orch.saveOutput(Task{ID: task.ID, OutputPath: task.OutputPath, Model: "small"}, "package models\n\nbroken")
+
+	if err := orch.repairGeneratedCode(context.Background(), []Task{task}); err != nil {
+		t.Fatalf("Unexpected repair error: %v", err)
+	}
+
+	// One repair round with the small model, then a fresh generation with the large one
+	if small.callCount != 1 || large.callCount != 1 {
+		t.Errorf("Expected small=1 large=1 calls, got small=%d large=%d", small.callCount, large.callCount)
+	}
+	if strings.Contains(escalated, "broken") {
+		t.Error("Escalation should start from the original prompt, not the failed code")
+	}
+
+	data, _ := os.ReadFile(filepath.Join(orch.workDir, "models.go"))
+	if !strings.Contains(string(data), "fixed") {
+		t.Errorf("Expected the large model's code, got:\n%s", data)
+	}
+
+	stored, _ := orch.storage.GetTask(task.ID)
+	if stored.Model != "large" {
+		t.Errorf("Expected task model large, got %q", stored.Model)
+	}
+
+	attempts, _ := orch.storage.GetAttempts(task.ID)
+	if len(attempts) != 2 || attempts[1].Phase != phaseEscalate {
+		t.Errorf("Expected a repair then an escalate attempt, got %+v", attempts)
+	}
+}
+
+// TestEscalationChecksOutput verifies escalated code is type-checked and
+// repaired before it is saved, like a first generation
+func TestEscalationChecksOutput(t *testing.T) {
+	small := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		return "package models\n\nbroken", nil
+	}}
+	largeReplies := []string{"package models\n\nvar count int = \"one\"", "package models\n\nvar count int = 1"}
+	large := &mockLLMProvider{completeFunc: func(ctx context.Context, prompt string) (string, error) {
+		reply := largeReplies[0]
+		largeReplies = largeReplies[1:]
+		return reply, nil
+	}}
+	orch := newFallbackOrchestrator(t, map[string]*mockLLMProvider{"small": small, "large": large}, "small", "large")
+	os.WriteFile(filepath.Join(orch.workDir, "go.mod"), []byte("module todo-api\n"), 0644)
+	orch.checker = validator.NewChecker(orch.workDir)
+	orch.validator = &fakeValidator{rounds: [][]validator.ValidationResult{
+		{{Tool: "go build", Output: "models.go:3:1: syntax error"}},
+		{{Tool: "go build", Output: "models.go:3:1: syntax error"}},
+		{{Tool: "go build", Success: true}},
+	}}
+
+	task := Task{ID: "run_1_models", PromptFile: "models.txt", OutputPath: "models.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusComplete)})
+	orch.storage.UpdateTaskModel(task.ID, "small")
+	orch.saveOutput(Task{ID: task.ID, OutputPath: task.OutputPath, Model: "small"}, "package models\n\nbroken")
+
+	if err := orch.repairGeneratedCode(context.Background(), []Task{task}); err != nil {
+		t.Fatalf("Unexpected repair error: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(orch.workDir, "models.go"))
+	if !strings.Contains(string(data), "var count int = 1") {
+		t.Errorf("Expected the type-checked escalation, got:\n%s", data)
+	}
+
+	var phases []string
+	attempts, _ := orch.storage.GetAttempts(task.ID)
+	for _, a := range attempts {
+		phases = append(phases, a.Phase+"/"+a.Model)
+	}
+	if fmt.Sprint(phases) != "[repair/small escalate/large repair/large]" {
+		t.Errorf("Expected the escalation to be repaired by the type check, got %v", phases)
+	}
+}
+
+// TestNextModel verifies movement along a task's model chain
+func TestNextModel(t *testing.T) {
+	orch := &Orchestrator{models: []string{"a", "b"}}
+
+	tests := []struct {
+		task Task
+		next string
+		ok   bool
+	}{
+		{Task{}, "a", true},
+		{Task{Model: "a"}, "b", true},
+		{Task{Model: "b"}, "", false},
+		{Task{Model: "a", Models: []string{"a", "c"}}, "c", true},
+		{Task{Model: "unknown"}, "a", true},
+	}
+
+	for _, tt := range tests {
+		next, ok := orch.nextModel(tt.task)
+		if next != tt.next || ok != tt.ok {
+			t.Errorf("nextModel(%q, %v) = %q, %v; want %q, %v", tt.task.Model, tt.task.Models, next, ok, tt.next, tt.ok)
+		}
+	}
+
+	// Without a chain the orchestrator's own provider is used
+	if _, ok := (&Orchestrator{}).nextModel(Task{}); ok {
+		t.Error("Expected no model without a chain")
+	}
+}
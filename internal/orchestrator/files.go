@@ -0,0 +1,288 @@
+package orchestrator
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// outputFile is one file of a task's output
+type outputFile struct {
+	Path    string // Relative to the work directory, slash-separated
+	Content string
+}
+
+// fileHeaderPattern matches a "// file: internal/x.go" line naming the file that follows
+// Multi-file outputs are stored with these headers between files
+var fileHeaderPattern = regexp.MustCompile(`(?i)^\s*//\s*(?:file|filename|path)\s*:\s*(\S+)\s*$`)
+
+// reservedPaths are written by the orchestrator itself, never by a task
+var reservedPaths = []string{"go.mod", "go.sum", "cmd/server/main.go"}
+
+// fence is a fenced code block found in an LLM response
+type fence struct {
+	Info   string // Text after the opening backticks, e.g. "go" or "go internal/x.go"
+	Body   string
+	Before string // Last non-empty line of text before the block
+}
+
+// parseResponseFiles splits an LLM response into the files it contains
+// Files are named by a path in a fence's info string, a path on the line
+// before a fence, or "// file:" headers. Unnamed code goes to defaultPath,
+// and a response naming no files at all is a single file at defaultPath
+func parseResponseFiles(raw, defaultPath string) ([]outputFile, error) {
+	var files []outputFile
+
+	fences := findFences(raw)
+	named := false
+	for _, f := range fences {
+		if f.path() != "" || hasFileHeader(f.Body) {
+			named = true
+			break
+		}
+	}
+
+	if named {
+		for _, f := range fences {
+			name := f.path()
+			if name == "" {
+				// Unnamed shell commands or config snippets aren't part of the code
+				if lang := f.language(); lang != "" && lang != "go" {
+					continue
+				}
+				name = defaultPath
+			}
+			files = appendFiles(files, splitFiles(f.Body, name))
+		}
+	} else {
+		files = splitFiles(extractCode(raw), defaultPath)
+	}
+
+	// The default path is the task's own output, which is checked with the pipeline
+	for _, file := range files {
+		if file.Path == defaultPath {
+			continue
+		}
+		if err := checkOutputPath(file.Path, defaultPath); err != nil {
+			return nil, fmt.Errorf("response names an invalid file: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// checkOutputPath returns an error if a task whose declared output is
+// defaultPath may not write p: the path must stay in the directory of
+// defaultPath (or below it) and not be one the orchestrator writes itself
+func checkOutputPath(p, defaultPath string) error {
+	if err := validateRelativePath(p); err != nil {
+		return err
+	}
+
+	p = path.Clean(p)
+	if dir := path.Dir(defaultPath); dir != "." && !strings.HasPrefix(p, dir+"/") {
+		return fmt.Errorf("path is outside the task's output directory %s: %s", dir, p)
+	}
+	if slices.Contains(reservedPaths, p) {
+		return fmt.Errorf("path is reserved for the orchestrator: %s", p)
+	}
+	return nil
+}
+
+// findFences returns every fenced code block in a response
+// An unterminated block runs to the end of the response, and the indentation
+// of the opening fence is removed from every line of the block
+func findFences(raw string) []fence {
+	var fences []fence
+	var current *fence
+	var body []string
+	before, indent := "", ""
+
+	for _, line := range strings.Split(raw, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current == nil {
+			if strings.HasPrefix(trimmed, "```") {
+				current = &fence{Info: strings.TrimSpace(strings.TrimLeft(trimmed, "`")), Before: before}
+				body = nil
+				// Blocks nested in markdown lists are indented as a whole
+				indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			} else if trimmed != "" {
+				before = trimmed
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "```") {
+			current.Body = strings.Join(body, "\n")
+			fences = append(fences, *current)
+			current = nil
+			before = ""
+			continue
+		}
+		body = append(body, strings.TrimPrefix(line, indent))
+	}
+
+	if current != nil {
+		current.Body = strings.Join(body, "\n")
+		fences = append(fences, *current)
+	}
+	return fences
+}
+
+// path returns the file a fenced block is labelled with, if any
+// Accepts "go internal/x.go", "go:internal/x.go", "go title=internal/x.go"
+// and a preceding line such as "**internal/x.go**" or "File: internal/x.go"
+func (f fence) path() string {
+	info := strings.ReplaceAll(f.Info, ":", " ")
+	for _, field := range strings.Fields(info) {
+		if _, value, ok := strings.Cut(field, "="); ok {
+			field = value
+		}
+		if p := asPath(field); p != "" {
+			return p
+		}
+	}
+
+	before := strings.Trim(f.Before, "*#`:_ ")
+	if label, rest, ok := strings.Cut(before, ":"); ok && !strings.ContainsAny(label, "/.") {
+		before = strings.Trim(rest, "*`_ ")
+	}
+	return asPath(before)
+}
+
+// asPath returns s cleaned of quotes if it looks like a relative file path
+func asPath(s string) string {
+	s = strings.Trim(s, `"'`+"`")
+	if s == "" || strings.ContainsAny(s, " \t") || strings.Contains(s, "://") {
+		return ""
+	}
+	if ext := path.Ext(s); ext == "" || ext == s {
+		return ""
+	}
+	return path.Clean(s)
+}
+
+// fileHeader returns the file named by line if it is a "// file:" header
+// A header must start its comment block, so prev must not be a comment, and
+// must name a file with an extension; this leaves comments such as
+// "// Path: /todos" or "// path: internal/models" in the code
+func fileHeader(line, prev string) string {
+	if strings.HasPrefix(strings.TrimSpace(prev), "//") {
+		return ""
+	}
+	match := fileHeaderPattern.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+	return asPath(match[1])
+}
+
+// hasFileHeader reports whether text contains a "// file:" header line
+func hasFileHeader(text string) bool {
+	prev := ""
+	for _, line := range strings.Split(text, "\n") {
+		if fileHeader(line, prev) != "" {
+			return true
+		}
+		prev = line
+	}
+	return false
+}
+
+// splitFiles splits text at "// file:" headers. Text before the first header
+// belongs to defaultPath; text without headers is a single file
+func splitFiles(text, defaultPath string) []outputFile {
+	var files []outputFile
+	current := outputFile{Path: defaultPath}
+	var lines []string
+
+	flush := func() {
+		current.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		if current.Content != "" {
+			files = appendFiles(files, []outputFile{current})
+		}
+	}
+
+	prev := ""
+	for _, line := range strings.Split(text, "\n") {
+		header := fileHeader(line, prev)
+		prev = line
+		if header != "" {
+			flush()
+			current = outputFile{Path: header}
+			lines = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	if len(files) == 0 {
+		return []outputFile{{Path: defaultPath, Content: strings.TrimSpace(text)}}
+	}
+	return files
+}
+
+// appendFiles adds files to a list, joining content for paths already present
+func appendFiles(files, more []outputFile) []outputFile {
+	for _, file := range more {
+		merged := false
+		for i := range files {
+			if files[i].Path == file.Path {
+				files[i].Content = strings.TrimSpace(files[i].Content + "\n\n" + file.Content)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// mergeFiles returns base with every file in update replacing the one at the same path
+func mergeFiles(base, update []outputFile) []outputFile {
+	result := append([]outputFile(nil), base...)
+	for _, file := range update {
+		replaced := false
+		for i := range result {
+			if result[i].Path == file.Path {
+				result[i] = file
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// joinFiles renders files as a single task output
+// A lone file at defaultPath is stored as-is; anything else gets "// file:" headers
+func joinFiles(files []outputFile, defaultPath string) string {
+	if len(files) == 1 && files[0].Path == defaultPath {
+		return files[0].Content
+	}
+
+	var b strings.Builder
+	for i, file := range files {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "// file: %s\n%s", file.Path, file.Content)
+	}
+	return b.String()
+}
+
+// filePaths returns the paths of files, slash-separated and cleaned
+func filePaths(files []outputFile) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = path.Clean(file.Path)
+	}
+	return paths
+}
@@ -0,0 +1,243 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/storage"
+)
+
+// TestParseResponseFiles verifies the ways models name the files of an answer
+func TestParseResponseFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		want  []outputFile
+		error bool
+	}{
+		{
+			name: "single unnamed block",
+			raw:  "```go\npackage main\n```",
+			want: []outputFile{{Path: "main.go", Content: "package main"}},
+		},
+		{
+			name: "plain code",
+			raw:  "package main\n\nfunc main() {}",
+			want: []outputFile{{Path: "main.go", Content: "package main\n\nfunc main() {}"}},
+		},
+		{
+			name: "file headers without fences",
+			raw:  "// file: internal/a/a.go\npackage a\n\n// file: internal/b/b.go\npackage b",
+			want: []outputFile{
+				{Path: "internal/a/a.go", Content: "package a"},
+				{Path: "internal/b/b.go", Content: "package b"},
+			},
+		},
+		{
+			name: "file headers after prose",
+			raw:  "Two files:\n\n// file: a.go\npackage a\n\nfunc A() {\n}\n\n// file: b.go\npackage b\n\nfunc B() {\n}\n\nBoth compile.",
+			want: []outputFile{
+				{Path: "a.go", Content: "package a\n\nfunc A() {\n}"},
+				{Path: "b.go", Content: "package b\n\nfunc B() {\n}"},
+			},
+		},
+		{
+			name: "file headers inside one fence",
+			raw:  "```go\n// File: a.go\npackage a\n// file: b.go\npackage b\n```",
+			want: []outputFile{
+				{Path: "a.go", Content: "package a"},
+				{Path: "b.go", Content: "package b"},
+			},
+		},
+		{
+			name: "paths in fence info strings",
+			raw:  "```go internal/a/a.go\npackage a\n```\n\n```go:internal/b/b.go\npackage b\n```\n```go title=\"c.go\"\npackage c\n```",
+			want: []outputFile{
+				{Path: "internal/a/a.go", Content: "package a"},
+				{Path: "internal/b/b.go", Content: "package b"},
+				{Path: "c.go", Content: "package c"},
+			},
+		},
+		{
+			name: "paths on the line before each fence",
+			raw:  "Here are the files.\n\n**internal/a/a.go**\n```go\npackage a\n```\n\nFile: `b.go`\n```go\npackage b\n```\n\n### c.go:\n```go\npackage c\n```\nThat's all.",
+			want: []outputFile{
+				{Path: "internal/a/a.go", Content: "package a"},
+				{Path: "b.go", Content: "package b"},
+				{Path: "c.go", Content: "package c"},
+			},
+		},
+		{
+			name: "unnamed block joins the default file",
+			raw:  "```go\npackage main\n```\n\n```go helper.go\npackage main\n\nfunc helper() {}\n```",
+			want: []outputFile{
+				{Path: "main.go", Content: "package main"},
+				{Path: "helper.go", Content: "package main\n\nfunc helper() {}"},
+			},
+		},
+		{
+			name: "path comments are not headers",
+			raw:  "```go\npackage main\n\n// Path: /todos\nfunc list() {}\n\n// ServeTodos handles the collection\n// path: internal/models\n// file: models.go\nfunc serve() {}\n```",
+			want: []outputFile{{Path: "main.go", Content: "package main\n\n// Path: /todos\nfunc list() {}\n\n// ServeTodos handles the collection\n// path: internal/models\n// file: models.go\nfunc serve() {}"}},
+		},
+		{
+			name:  "path escaping the work directory",
+			raw:   "// file: ../../etc/passwd.go\npackage evil",
+			error: true,
+		},
+		{
+			name:  "absolute path",
+			raw:   "```go /tmp/x.go\npackage x\n```",
+			error: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponseFiles(tt.raw, "main.go")
+			if tt.error {
+				if err == nil {
+					t.Fatalf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestParseResponseFilesOutputDir verifies a response can only add files
+// next to or below the task's own output
+func TestParseResponseFilesOutputDir(t *testing.T) {
+	tests := []struct {
+		raw   string
+		error bool
+	}{
+		{raw: "// file: internal/models/user.go\npackage models", error: false},
+		{raw: "// file: internal/models/db/db.go\npackage db", error: false},
+		{raw: "// file: internal/handlers/todo_handler.go\npackage handlers", error: true},
+		{raw: "// file: cmd/server/main.go\npackage main", error: true},
+		{raw: "```go go.mod\nmodule evil\n```", error: true},
+	}
+
+	for _, tt := range tests {
+		_, err := parseResponseFiles(tt.raw, "internal/models/todo.go")
+		if tt.error != (err != nil) {
+			t.Errorf("parseResponseFiles(%q): expected error %v, got %v", tt.raw, tt.error, err)
+		}
+	}
+
+	// At the root, only the files the orchestrator writes itself are refused
+	if _, err := parseResponseFiles("```go go.mod\nmodule evil\n```", "main.go"); err == nil {
+		t.Error("Expected go.mod to be refused")
+	}
+}
+
+// TestJoinSplitFiles verifies task outputs round-trip through the stored format
+func TestJoinSplitFiles(t *testing.T) {
+	single := []outputFile{{Path: "main.go", Content: "package main"}}
+	if got := joinFiles(single, "main.go"); got != "package main" {
+		t.Errorf("Expected a lone default file stored as-is, got %q", got)
+	}
+
+	multi := []outputFile{
+		{Path: "main.go", Content: "package main"},
+		{Path: "internal/x/x.go", Content: "package x"},
+	}
+	joined := joinFiles(multi, "main.go")
+	if !strings.HasPrefix(joined, "// file: main.go\n") {
+		t.Errorf("Expected file headers, got %q", joined)
+	}
+	if got := splitFiles(joined, "main.go"); !reflect.DeepEqual(got, multi) {
+		t.Errorf("Expected %+v after round trip, got %+v", multi, got)
+	}
+
+	// Replies to a repair prompt may only contain the files that changed
+	merged := mergeFiles(multi, []outputFile{{Path: "internal/x/x.go", Content: "package x // fixed"}})
+	if merged[0].Content != "package main" || merged[1].Content != "package x // fixed" {
+		t.Errorf("Unexpected merge result: %+v", merged)
+	}
+}
+
+// TestSaveMultiFileOutput verifies every file of an output is written and recorded
+func TestSaveMultiFileOutput(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	store := storage.NewStorage(db)
+	orch := &Orchestrator{storage: store, workDir: workDir}
+
+	task := Task{ID: "run_1_models", OutputPath: "internal/models/todo.go"}
+	if err := store.CreateTask(storage.Task{ID: task.ID, Type: "models", Status: "pending"}); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	files := []outputFile{
+		{Path: "internal/models/todo.go", Content: "package models\n\ntype Todo struct{}"},
+		{Path: "internal/models/user.go", Content: "package models\n\ntype User struct{}"},
+	}
+	content := joinFiles(files, task.OutputPath)
+	if err := orch.saveOutput(task, content); err != nil {
+		t.Fatalf("Failed to save output: %v", err)
+	}
+	if err := store.UpdateTaskOutput(task.ID, content); err != nil {
+		t.Fatalf("Failed to update task output: %v", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(workDir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file.Path, err)
+		}
+		if string(data) != file.Content {
+			t.Errorf("Expected %s to contain %q, got %q", file.Path, file.Content, data)
+		}
+	}
+
+	records, err := store.GetGeneratedFiles(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to load file records: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("Expected 2 file records, got %d", len(records))
+	}
+
+	// Reading the output back picks up changes made on disk
+	userPath := filepath.Join(workDir, "internal", "models", "user.go")
+	os.WriteFile(userPath, []byte("package models\n\ntype User struct {\n}\n"), 0644)
+	read, err := orch.readOutput(task)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if !strings.Contains(read, "// file: internal/models/user.go\npackage models\n\ntype User struct {\n}") {
+		t.Errorf("Expected the formatted file in the output, got:\n%s", read)
+	}
+
+	// Another task's declared output can't be overwritten
+	orch.tasks = map[string]Task{
+		task.ID:          task,
+		"run_1_handlers": {ID: "run_1_handlers", OutputPath: "internal/models/handlers.go"},
+	}
+	err = orch.saveOutput(task, "// file: internal/models/handlers.go\npackage models")
+	if err == nil || !strings.Contains(err.Error(), "run_1_handlers") {
+		t.Errorf("Expected error for another task's output, got %v", err)
+	}
+
+	// A bad path in a stored bundle fails before anything is written
+	err = orch.saveOutput(task, "// file: ../escape.go\npackage escape")
+	if err == nil {
+		t.Error("Expected error for a file outside the work directory")
+	}
+	if _, statErr := os.Stat(filepath.Join(filepath.Dir(workDir), "escape.go")); statErr == nil {
+		t.Error("File outside the work directory was written")
+	}
+}
@@ -0,0 +1,363 @@
+package orchestrator
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stdPackages maps the names of commonly used standard library packages to
+// their import paths. Names shared by several packages (rand, template) map
+// to the one generated code almost always means
+var stdPackages = map[string]string{
+	"atomic":   "sync/atomic",
+	"base64":   "encoding/base64",
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"cmp":      "cmp",
+	"context":  "context",
+	"csv":      "encoding/csv",
+	"errors":   "errors",
+	"exec":     "os/exec",
+	"filepath": "path/filepath",
+	"fmt":      "fmt",
+	"fs":       "io/fs",
+	"hex":      "encoding/hex",
+	"http":     "net/http",
+	"httptest": "net/http/httptest",
+	"io":       "io",
+	"json":     "encoding/json",
+	"log":      "log",
+	"maps":     "maps",
+	"math":     "math",
+	"net":      "net",
+	"os":       "os",
+	"path":     "path",
+	"rand":     "math/rand",
+	"reflect":  "reflect",
+	"regexp":   "regexp",
+	"runtime":  "runtime",
+	"sha256":   "crypto/sha256",
+	"signal":   "os/signal",
+	"slices":   "slices",
+	"slog":     "log/slog",
+	"sort":     "sort",
+	"sql":      "database/sql",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"syscall":  "syscall",
+	"template": "text/template",
+	"testing":  "testing",
+	"time":     "time",
+	"unicode":  "unicode",
+	"url":      "net/url",
+	"utf8":     "unicode/utf8",
+}
+
+// importSpec is a single import of a Go file
+type importSpec struct {
+	Name    string // Explicit name, "_" or "."; empty for the default
+	Path    string
+	Doc     string // Comment lines above the import, if any
+	Comment string // Comment after the import on the same line, if any
+}
+
+// fixImports rewrites the imports of a generated Go file
+// Relative imports such as "../models" become paths within module, missing
+// standard library imports are added and unused ones removed. Third-party
+// imports are only removed when they have an explicit name, since their
+// package name can't be known. Names are resolved against the whole package,
+// so siblings holds the source of the package's other files. Source that
+// doesn't parse, or needs no changes, is returned unchanged
+func fixImports(filePath, src, module string, siblings map[string]string) string {
+	if path.Ext(filePath) != ".go" {
+		return src
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	used := usedPackages(fset, file, packageFiles(fset, file, filePath, siblings))
+	imported := make(map[string]bool)
+	changed := false
+
+	var imports []importSpec
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || importPath == "C" {
+			// cgo preambles are tied to their import, so leave such files alone
+			return src
+		}
+		imp := importSpec{Path: importPath, Doc: commentText(spec.Doc), Comment: commentText(spec.Comment)}
+		if spec.Name != nil {
+			imp.Name = spec.Name.Name
+		}
+
+		if resolved, ok := resolveRelativeImport(filePath, importPath, module); ok {
+			imp.Path = resolved
+			changed = true
+		}
+
+		name := imp.Name
+		if name == "" {
+			name = defaultPackageName(imp.Path)
+		}
+		removable := imp.Name != "" || isStdPath(imp.Path, module)
+		if name != "_" && name != "." && removable && !used[name] {
+			changed = true
+			continue
+		}
+
+		imported[name] = true
+		imports = append(imports, imp)
+	}
+
+	// Add standard library packages that are referenced but not imported
+	var missing []string
+	for name := range used {
+		if importPath, ok := stdPackages[name]; ok && !imported[name] {
+			missing = append(missing, importPath)
+		}
+	}
+	sort.Strings(missing)
+	for _, importPath := range missing {
+		imports = append(imports, importSpec{Path: importPath})
+		changed = true
+	}
+
+	if !changed {
+		return src
+	}
+
+	// Replace the import declarations with a freshly rendered block
+	tf := fset.File(file.Pos())
+	start, end := tf.Offset(file.Name.End()), tf.Offset(file.Name.End())
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if len(decls) == 0 {
+				start = tf.Offset(gen.Pos())
+			}
+			end = tf.Offset(gen.End())
+			decls = append(decls, decl)
+		}
+	}
+
+	block := renderImports(imports, module)
+	if len(decls) == 0 {
+		block = "\n\n" + block
+	} else if floating := floatingComments(file, decls); floating != "" {
+		// Comments in the import block that belong to no import stay above it
+		block = floating + "\n" + block
+	}
+	fixed, err := format.Source([]byte(src[:start] + block + src[end:]))
+	if err != nil {
+		return src
+	}
+	return strings.TrimSpace(string(fixed))
+}
+
+// usedPackages returns the names used as the qualifier of an exported
+// identifier that are either imported packages or not declared anywhere in
+// the package, i.e. likely missing imports. Names are resolved with go/types
+// against file and the package's other files, with every import stubbed as
+// an empty package
+func usedPackages(fset *token.FileSet, file *ast.File, others []*ast.File) map[string]bool {
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{
+		Importer: stubImporter{},
+		Error:    func(error) {}, // Missing imports and stubbed members are expected
+	}
+	conf.Check(file.Name.Name, fset, append([]*ast.File{file}, others...), info)
+
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || !ast.IsExported(sel.Sel.Name) {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		switch info.Uses[ident].(type) {
+		case nil, *types.PkgName:
+			used[ident.Name] = true
+		}
+		return true
+	})
+	return used
+}
+
+// stubImporter imports every path as an empty package named after the path
+type stubImporter struct{}
+
+func (stubImporter) Import(importPath string) (*types.Package, error) {
+	pkg := types.NewPackage(importPath, defaultPackageName(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// packageFiles parses the siblings of a file that belong to the same package
+// Regular files can't see declarations in test files, so those are only
+// included for test files. Siblings that don't parse are skipped
+func packageFiles(fset *token.FileSet, file *ast.File, filePath string, siblings map[string]string) []*ast.File {
+	isTest := strings.HasSuffix(filePath, "_test.go")
+
+	var names []string
+	for name := range siblings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []*ast.File
+	for _, name := range names {
+		if name == filePath || path.Ext(name) != ".go" || (!isTest && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		sibling, err := parser.ParseFile(fset, name, siblings[name], parser.SkipObjectResolution)
+		if err != nil || sibling.Name.Name != file.Name.Name {
+			continue
+		}
+		files = append(files, sibling)
+	}
+	return files
+}
+
+// commentText returns the comments of a group one per line, or "" for none
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	lines := make([]string, len(group.List))
+	for i, c := range group.List {
+		lines[i] = c.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// floatingComments returns the comments inside import declarations that are
+// attached to no import, one per line
+func floatingComments(file *ast.File, decls []ast.Decl) string {
+	attached := make(map[*ast.CommentGroup]bool)
+	for _, spec := range file.Imports {
+		attached[spec.Doc] = true
+		attached[spec.Comment] = true
+	}
+
+	var lines []string
+	for _, group := range file.Comments {
+		if attached[group] {
+			continue
+		}
+		for _, decl := range decls {
+			gen := decl.(*ast.GenDecl)
+			if gen.Lparen.IsValid() && group.Pos() > gen.Lparen && group.End() < gen.Rparen {
+				lines = append(lines, commentText(group))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolveRelativeImport turns "./x" and "../x" imports into module paths
+// ok is false for other imports and for paths escaping the module
+func resolveRelativeImport(filePath, importPath, module string) (string, bool) {
+	if !strings.HasPrefix(importPath, "./") && !strings.HasPrefix(importPath, "../") {
+		return "", false
+	}
+
+	dir := path.Join(path.Dir(filePath), importPath)
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", false
+	}
+	if dir == "." {
+		return module, true
+	}
+	return module + "/" + dir, true
+}
+
+// isStdPath reports whether an import path belongs to the standard library
+// Paths without a dot in their first element are standard unless in module
+func isStdPath(importPath, module string) bool {
+	if importPath == module || strings.HasPrefix(importPath, module+"/") {
+		return false
+	}
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// defaultPackageName returns the name an import is referred to by without an
+// explicit name, skipping major version suffixes such as "math/rand/v2"
+func defaultPackageName(importPath string) string {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name
+}
+
+// renderImports formats imports as a declaration, standard library first
+func renderImports(imports []importSpec, module string) string {
+	var std, other []importSpec
+	for _, imp := range imports {
+		if isStdPath(imp.Path, module) {
+			std = append(std, imp)
+		} else {
+			other = append(other, imp)
+		}
+	}
+	if len(std)+len(other) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if len(imports) == 1 {
+		if imports[0].Doc != "" {
+			b.WriteString(imports[0].Doc + "\n")
+		}
+		b.WriteString("import ")
+		if imports[0].Name != "" {
+			b.WriteString(imports[0].Name + " ")
+		}
+		b.WriteString(strconv.Quote(imports[0].Path))
+		if imports[0].Comment != "" {
+			b.WriteString(" " + imports[0].Comment)
+		}
+		return b.String()
+	}
+
+	b.WriteString("import (\n")
+	for i, group := range [][]importSpec{std, other} {
+		if i > 0 && len(std) > 0 && len(other) > 0 {
+			b.WriteString("\n")
+		}
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Path < group[j].Path })
+		for _, imp := range group {
+			if imp.Doc != "" {
+				b.WriteString("\t" + strings.ReplaceAll(imp.Doc, "\n", "\n\t") + "\n")
+			}
+			b.WriteString("\t")
+			if imp.Name != "" {
+				b.WriteString(imp.Name + " ")
+			}
+			b.WriteString(strconv.Quote(imp.Path))
+			if imp.Comment != "" {
+				b.WriteString(" " + imp.Comment)
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(")")
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestFixImports verifies the import problems seen in generated code are fixed
+func TestFixImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		src      string
+		siblings map[string]string
+		want     string
+	}{
+		{
+			name: "adds missing standard library imports",
+			path: "internal/models/todo.go",
+			src:  "package models\n\nfunc Sorted(ids []int) error {\n\tsort.Ints(ids)\n\treturn errors.New(\"x\")\n}\n",
+			want: "package models\n\nimport (\n\t\"errors\"\n\t\"sort\"\n)\n\nfunc Sorted(ids []int) error {\n\tsort.Ints(ids)\n\treturn errors.New(\"x\")\n}",
+		},
+		{
+			name: "removes unused imports",
+			path: "internal/models/todo.go",
+			src:  "package models\n\nimport (\n\t\"database/sql\"\n\t\"time\"\n)\n\ntype Todo struct{ Created time.Time }\n",
+			want: "package models\n\nimport \"time\"\n\ntype Todo struct{ Created time.Time }",
+		},
+		{
+			name: "rewrites relative imports",
+			path: "internal/handlers/todo.go",
+			src:  "package handlers\n\nimport (\n\t\"net/http\"\n\n\t\"../models\"\n)\n\nvar _ models.Todo\nvar _ http.Handler\n",
+			want: "package handlers\n\nimport (\n\t\"net/http\"\n\n\t\"todo-api/internal/models\"\n)\n\nvar _ models.Todo\nvar _ http.Handler",
+		},
+		{
+			name: "keeps blank and third-party imports",
+			path: "main.go",
+			src:  "package main\n\nimport (\n\t_ \"github.com/mattn/go-sqlite3\"\n\t\"github.com/gorilla/mux\"\n)\n\nfunc main() {}\n",
+			want: "package main\n\nimport (\n\t_ \"github.com/mattn/go-sqlite3\"\n\t\"github.com/gorilla/mux\"\n)\n\nfunc main() {}\n",
+		},
+		{
+			name: "ignores local variables named like packages",
+			path: "main.go",
+			src:  "package main\n\ntype T struct{ Name string }\n\nfunc f(strings T) string { return strings.Name }\n",
+			want: "package main\n\ntype T struct{ Name string }\n\nfunc f(strings T) string { return strings.Name }\n",
+		},
+		{
+			name:     "resolves names declared in other files of the package",
+			path:     "internal/handlers/todo.go",
+			src:      "package handlers\n\nfunc f() string { return json.Name }\n",
+			siblings: map[string]string{"internal/handlers/vars.go": "package handlers\n\nvar json struct{ Name string }\n"},
+			want:     "package handlers\n\nfunc f() string { return json.Name }\n",
+		},
+		{
+			name:     "ignores siblings from other packages and test files",
+			path:     "internal/handlers/todo.go",
+			src:      "package handlers\n\nfunc f() ([]byte, error) { return json.Marshal(nil) }\n",
+			siblings: map[string]string{"internal/handlers/todo_test.go": "package handlers\n\nvar json struct{ Marshal func(any) ([]byte, error) }\n"},
+			want:     "package handlers\n\nimport \"encoding/json\"\n\nfunc f() ([]byte, error) { return json.Marshal(nil) }",
+		},
+		{
+			name: "keeps comments in the import block",
+			path: "main.go",
+			src:  "package main\n\nimport (\n\t// Logging\n\t\"log\"\n\t\"os\"\n\n\t// drivers\n\n\t_ \"github.com/mattn/go-sqlite3\" // registers sqlite3\n)\n\nfunc main() { log.Println(time.Now()) }\n",
+			want: "package main\n\n// drivers\nimport (\n\t// Logging\n\t\"log\"\n\t\"time\"\n\n\t_ \"github.com/mattn/go-sqlite3\" // registers sqlite3\n)\n\nfunc main() { log.Println(time.Now()) }",
+		},
+		{
+			name: "leaves unparseable code alone",
+			path: "main.go",
+			src:  "package main\n\nfunc main() { fmt.Println(\n",
+			want: "package main\n\nfunc main() { fmt.Println(\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fixImports(tt.path, tt.src, "todo-api", tt.siblings); got != tt.want {
+				t.Errorf("Unexpected result\n--- got ---\n%s\n--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSiblingSources verifies imports are fixed against the response's other
+// files and the package's files already in the work directory
+func TestSiblingSources(t *testing.T) {
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, "internal", "models")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "db.go"), []byte("package models\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "todo.go"), []byte("package models // old"), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# models"), 0644)
+
+	orch := &Orchestrator{workDir: workDir}
+	files := []outputFile{
+		{Path: "internal/models/todo.go", Content: "package models"},
+		{Path: "internal/models/user.go", Content: "package models // new"},
+		{Path: "internal/handlers/todo.go", Content: "package handlers"},
+	}
+	got := orch.siblingSources(files, "internal/models/todo.go")
+	want := map[string]string{
+		"internal/models/db.go":   "package models\n",
+		"internal/models/user.go": "package models // new",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
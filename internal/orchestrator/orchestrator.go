@@ -5,19 +5,26 @@ import (
 	"database/sql"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"gorchestrator-poc/internal/llm"
 	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+	"gorchestrator-poc/prompts"
 )
 
 // LLMProvider defines the interface for LLM interactions
 // Implementations must provide code completion capabilities
 type LLMProvider interface {
-	Complete(ctx context.Context, prompt string) (string, error)
+	Complete(ctx context.Context, prompt string, opts llm.Options) (string, error)
 	HealthCheck(ctx context.Context) error
 }
 
@@ -43,14 +50,23 @@ const (
 
 // Task represents a single code generation task
 type Task struct {
-	ID        string
-	Type      TaskType
-	Input     string
-	Output    string
-	Status    TaskStatus
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Error     string
+	ID          string
+	Name        string // Pipeline task ID, without the run prefix
+	Type        TaskType
+	Input       string
+	PromptFile  string      // Prompt template file, relative to the prompts directory
+	OutputPath  string      // Output file, relative to the work directory
+	DependsOn   []string    // Run-scoped IDs of tasks that must complete first
+	ContextMode string      // How dependency outputs are added to the prompt (ContextAPI by default)
+	Options     llm.Options // Generation options for every LLM call of the task
+	Models      []string    // Model chain for the task; empty uses the orchestrator's chain
+	Model       string      // Model the task is currently generated with
+	Candidates  int         // Completions to choose from; zero uses the orchestrator's default
+	Output      string
+	Status      TaskStatus
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Error       string
 }
 
 // SafetyLimits defines operational boundaries for safe execution
@@ -63,12 +79,33 @@ type SafetyLimits struct {
 // Orchestrator manages the code generation pipeline
 // Coordinates LLM calls, stores results, and ensures safety limits
 type Orchestrator struct {
-	llm         LLMProvider
-	storage     *storage.Storage
-	workDir     string
-	limits      SafetyLimits
-	promptsPath string
-	startTime   time.Time
+	llm          LLMProvider
+	storage      *storage.Storage
+	workDir      string
+	limits       SafetyLimits
+	promptsPath  string // Optional override directory, checked before promptsFS
+	promptsFS    fs.FS  // Default prompt templates
+	module       string
+	project      string            // Project description passed to prompt templates
+	entities     []string          // Entity names declared by the pipeline
+	vars         map[string]string // Template variables of the current run
+	cliVars      map[string]string // Template variables set on the command line
+	options      llm.Options       // Generation options set on the command line
+	runID        string
+	tasks        map[string]Task        // Every task of the current run, by ID
+	workers      int                    // Maximum number of tasks executed concurrently
+	validator    CodeValidator          // Drives the repair loop; nil disables repair
+	checker      SourceChecker          // Checks each task's output before it is saved; nil skips it
+	backoff      BackoffPolicy          // Delay between retries of failed LLM calls
+	progress     ProgressFunc           // Optional live progress of streaming LLM calls
+	cache        *responseCache         // Optional LLM response cache; nil calls the LLM every time
+	models       []string               // Default model chain; empty uses llm for every task
+	resolveModel ModelResolver          // Creates providers for the models in a chain
+	providers    map[string]LLMProvider // Resolved providers, by model
+	providersMu  sync.Mutex
+	candidates   int        // Default number of completions generated per task
+	candidateMu  sync.Mutex // Serialises validation of candidates in the work directory
+	startTime    time.Time
 }
 
 // GenerationStats tracks statistics for the generation session
@@ -84,10 +121,13 @@ type GenerationStats struct {
 // New creates a new Orchestrator instance with default safety limits
 func New(llm LLMProvider, db *sql.DB, workDir string) *Orchestrator {
 	return &Orchestrator{
-		llm:         llm,
-		storage:     storage.NewStorage(db),
-		workDir:     workDir,
-		promptsPath: "prompts",
+		llm:        llm,
+		storage:    storage.NewStorage(db),
+		workDir:    workDir,
+		promptsFS:  prompts.FS,
+		workers:    1,
+		candidates: 1,
+		backoff:    defaultBackoff,
 		limits: SafetyLimits{
 			MaxRetries:    3,
 			MaxRuntime:    30 * time.Minute,
@@ -96,14 +136,36 @@ func New(llm LLMProvider, db *sql.DB, workDir string) *Orchestrator {
 	}
 }
 
-// GenerateTodoAPI runs the main code generation pipeline
-// Executes a fixed sequence of tasks to generate a complete Todo REST API
+// SetPromptsDir sets a directory whose prompt files take precedence over the
+// embedded defaults. Files missing from the directory fall back to the defaults
+func (o *Orchestrator) SetPromptsDir(dir string) {
+	o.promptsPath = dir
+}
+
+// SetOptions sets the generation options for every task
+// Options declared by the pipeline or its tasks take precedence
+func (o *Orchestrator) SetOptions(opts llm.Options) {
+	o.options = llm.DefaultOptions().Merge(opts)
+}
+
+// SetWorkers sets how many independent tasks may run concurrently
+// Values below one are treated as one (strictly sequential execution)
+func (o *Orchestrator) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	o.workers = n
+}
+
+// GenerateTodoAPI runs the built-in pipeline to generate a complete Todo REST API
 func (o *Orchestrator) GenerateTodoAPI(ctx context.Context, projectName string) error {
-	o.startTime = time.Now()
+	return o.Generate(ctx, DefaultPipeline(), projectName)
+}
 
-	// Apply global timeout for safety
-	ctx, cancel := context.WithTimeout(ctx, o.limits.MaxRuntime)
-	defer cancel()
+// Generate runs the code generation pipeline
+// Tasks run in dependency order, with independent tasks executed concurrently
+func (o *Orchestrator) Generate(ctx context.Context, p *Pipeline, projectName string) error {
+	o.startTime = time.Now()
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(o.workDir, 0755); err != nil {
@@ -111,65 +173,92 @@ func (o *Orchestrator) GenerateTodoAPI(ctx context.Context, projectName string)
 	}
 
 	// Generate unique run ID to avoid database conflicts
-	runID := fmt.Sprintf("run_%d", time.Now().Unix())
-	fmt.Printf("Run ID: %s\n\n", runID)
+	o.runID = fmt.Sprintf("run_%d", time.Now().Unix())
+	fmt.Printf("Run ID: %s\n\n", o.runID)
 
-	// Define the fixed pipeline of tasks with unique IDs per run
-	tasks := []Task{
-		{ID: fmt.Sprintf("%s_task_001", runID), Type: TaskGenerateModels, Input: "Todo with CRUD", Status: StatusPending},
-		{ID: fmt.Sprintf("%s_task_002", runID), Type: TaskGenerateHandlers, Input: "REST endpoints", Status: StatusPending},
-		{ID: fmt.Sprintf("%s_task_003", runID), Type: TaskGenerateRepository, Input: "SQLite storage", Status: StatusPending},
-		{ID: fmt.Sprintf("%s_task_004", runID), Type: TaskGenerateTests, Input: "Unit tests", Status: StatusPending},
+	// Store the pipeline with the run so it can be resumed later
+	p = p.withVars(o.cliVars).withOptions(o.options)
+	o.project = projectName
+	pipelineJSON, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode pipeline: %w", err)
 	}
+	if err := o.storage.CreateRun(storage.Run{
+		ID:       o.runID,
+		Pipeline: string(pipelineJSON),
+		Project:  projectName,
+		WorkDir:  o.workDir,
+		Status:   string(StatusRunning),
+	}); err != nil {
+		return fmt.Errorf("failed to create run %s: %w", o.runID, err)
+	}
+
+	// Build the pipeline's tasks with unique IDs per run
+	tasks := p.newTasks(o.runID)
 
 	// Store tasks in database
 	for _, task := range tasks {
-		if err := o.storage.CreateTask(storage.Task{
-			ID:     task.ID,
-			Type:   string(task.Type),
-			Input:  task.Input,
-			Status: string(task.Status),
-		}); err != nil {
-			return fmt.Errorf("failed to create task %s: %w", task.ID, err)
+		if err := o.createTask(task); err != nil {
+			return err
 		}
 	}
 
-	// Execute each task in sequence
-	for _, task := range tasks {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("generation timeout exceeded")
-		default:
-			if err := o.executeTask(ctx, task); err != nil {
-				o.logError(task.ID, err)
-				return fmt.Errorf("task %s (%s) failed: %w", task.ID, task.Type, err)
-			}
-			fmt.Printf("[DONE] Completed: %s\n", task.Type)
-		}
-	}
+	return o.execute(ctx, p, tasks, tasks)
+}
 
-	// Generate server main.go entry point
-	if err := o.generateServerMain(); err != nil {
-		return fmt.Errorf("failed to generate server main: %w", err)
+// RunID returns the ID of the current (or most recent) run
+func (o *Orchestrator) RunID() string {
+	return o.runID
+}
+
+// execute runs the pending tasks of a run and finishes the generated project
+// all holds every task of the run, including ones completed by an earlier attempt
+func (o *Orchestrator) execute(ctx context.Context, p *Pipeline, all, pending []Task) error {
+	o.module = p.Module
+	o.entities = p.Entities
+	o.vars = p.Vars
+	o.tasks = make(map[string]Task, len(all))
+	for _, task := range all {
+		o.tasks[task.ID] = task
 	}
-	fmt.Printf("[DONE] Completed: server main.go\n")
 
-	// Generate go.mod for the output project
+	// Apply global timeout for safety
+	ctx, cancel := context.WithTimeout(ctx, o.limits.MaxRuntime)
+	defer cancel()
+
+	// Write go.mod first so candidates can be built while tasks run
 	if err := o.generateGoMod(); err != nil {
 		return fmt.Errorf("failed to generate go.mod: %w", err)
 	}
 
+	// Execute tasks as their dependencies complete
+	if err := runDAG(ctx, pending, o.workers, o.runTask); err != nil {
+		o.updateRunStatus(StatusFailed)
+		return err
+	}
+
+	// Generate server main.go entry point
+	if p.Scaffold == ScaffoldTodoAPI {
+		if err := o.generateServerMain(); err != nil {
+			return fmt.Errorf("failed to generate server main: %w", err)
+		}
+		fmt.Printf("[DONE] Completed: server main.go\n")
+	}
+
 	// Generate README for the output project
-	if err := o.generateREADME(); err != nil {
+	if err := o.generateREADME(p); err != nil {
 		return fmt.Errorf("failed to generate README: %w", err)
 	}
 
-	// Run validation on generated code
-	if err := o.validateGeneratedCode(ctx); err != nil {
-		fmt.Printf("WARNING: Validation issues: %v\n", err)
-		// Don't fail on validation errors for PoC
+	// Repair compile errors reported by the Go toolchain
+	if o.validator != nil {
+		if err := o.repairGeneratedCode(ctx, all); err != nil {
+			fmt.Printf("WARNING: Repair incomplete: %v\n", err)
+		}
 	}
 
+	o.updateRunStatus(StatusComplete)
+
 	// Write status file for monitoring
 	if err := o.writeStatusFile(); err != nil {
 		fmt.Printf("Failed to write status file: %v\n", err)
@@ -179,45 +268,40 @@ func (o *Orchestrator) GenerateTodoAPI(ctx context.Context, projectName string)
 	return nil
 }
 
-// cleanLLMOutput removes markdown code blocks and extra text from LLM output
-// This is critical because LLMs often wrap code in ```go blocks despite instructions
-func cleanLLMOutput(raw string) string {
-	// Trim whitespace
-	raw = strings.TrimSpace(raw)
-	
-	// Check if the output starts with markdown code block
-	if strings.HasPrefix(raw, "```") {
-		lines := strings.Split(raw, "\n")
-		var cleaned []string
-		inCode := false
-		
-		for _, line := range lines {
-			// Check for code block markers
-			if strings.HasPrefix(strings.TrimSpace(line), "```") {
-				inCode = !inCode
-				continue // Skip the markdown markers
-			}
-			
-			// Only include lines that are inside code blocks
-			if inCode {
-				cleaned = append(cleaned, line)
-			}
-		}
-		
-		// Join the cleaned lines
-		result := strings.Join(cleaned, "\n")
-		
-		// Trim any trailing whitespace
-		return strings.TrimSpace(result)
+// createTask stores a new pending task in the database
+func (o *Orchestrator) createTask(task Task) error {
+	if err := o.storage.CreateTask(storage.Task{
+		ID:     task.ID,
+		Type:   string(task.Type),
+		Input:  task.Input,
+		Status: string(task.Status),
+	}); err != nil {
+		return fmt.Errorf("failed to create task %s: %w", task.ID, err)
 	}
-	
-	// If no markdown blocks found, check for inline backticks at start/end
-	if strings.HasPrefix(raw, "`") && strings.HasSuffix(raw, "`") {
-		raw = strings.TrimPrefix(raw, "`")
-		raw = strings.TrimSuffix(raw, "`")
+	return nil
+}
+
+// updateRunStatus records the run's status; failures are only logged
+func (o *Orchestrator) updateRunStatus(status TaskStatus) {
+	if err := o.storage.UpdateRunStatus(o.runID, string(status)); err != nil {
+		fmt.Printf("Failed to update status of run %s: %v\n", o.runID, err)
 	}
-	
-	return raw
+}
+
+// runTask executes a scheduled task and records any failure
+func (o *Orchestrator) runTask(ctx context.Context, task Task) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("generation timeout exceeded")
+	default:
+	}
+
+	if err := o.executeTask(ctx, task); err != nil {
+		o.logError(task.ID, err)
+		return fmt.Errorf("task %s (%s) failed: %w", task.ID, task.Type, err)
+	}
+	fmt.Printf("[DONE] Completed: %s\n", task.Type)
+	return nil
 }
 
 // executeTask runs a single code generation task
@@ -227,26 +311,18 @@ func (o *Orchestrator) executeTask(ctx context.Context, task Task) error {
 		return fmt.Errorf("failed to update task status: %w", err)
 	}
 
-	// Load the appropriate prompt template
-	prompt, err := o.loadPrompt(task.Type)
+	// Load the prompt template along with the code of its dependencies
+	messages, err := o.buildPrompt(task)
 	if err != nil {
 		return fmt.Errorf("failed to load prompt: %w", err)
 	}
 
-	// Call LLM for code generation
+	// Call LLM for code generation, fixing syntax and type errors before anything is written
 	fmt.Printf("  → Generating %s...\n", task.Type)
-	response, err := o.llm.Complete(ctx, prompt)
+	cleaned, task, err := o.generateOutput(ctx, task, phaseGenerate, messages)
 	if err != nil {
 		o.storage.UpdateTaskStatus(task.ID, string(StatusFailed))
-		return fmt.Errorf("LLM generation failed: %w", err)
-	}
-
-	// Clean the LLM output to remove markdown formatting
-	cleaned := cleanLLMOutput(response)
-	
-	// Check output size limit
-	if len(cleaned) > o.limits.MaxOutputSize {
-		return fmt.Errorf("output exceeds size limit: %d > %d", len(cleaned), o.limits.MaxOutputSize)
+		return err
 	}
 
 	// Show preview of cleaned output
@@ -272,71 +348,196 @@ func (o *Orchestrator) executeTask(ctx context.Context, task Task) error {
 	return nil
 }
 
-// loadPrompt reads the prompt template for a given task type
-func (o *Orchestrator) loadPrompt(taskType TaskType) (string, error) {
-	// Map task types to prompt files
-	promptFile := ""
-	switch taskType {
-	case TaskGenerateModels:
-		promptFile = "generate_models.txt"
-	case TaskGenerateHandlers:
-		promptFile = "generate_handlers.txt"
-	case TaskGenerateRepository:
-		promptFile = "generate_repository.txt"
-	case TaskGenerateTests:
-		promptFile = "generate_tests.txt"
-	default:
-		return "", fmt.Errorf("unknown task type: %s", taskType)
+// generateCode sends a task's conversation to the LLM and returns the cleaned code
+// The output size limit is enforced on the cleaned result
+func (o *Orchestrator) generateCode(ctx context.Context, task Task, messages []llm.Message) (string, error) {
+	response, err := o.complete(ctx, task, messages)
+	if err != nil {
+		return "", fmt.Errorf("LLM generation failed: %w", err)
+	}
+
+	// Split the response into files and remove markdown formatting
+	files, err := parseResponseFiles(response, task.OutputPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Add missing imports, drop unused ones and make relative ones absolute
+	for i, file := range files {
+		files[i].Content = fixImports(file.Path, file.Content, o.modulePath(), o.siblingSources(files, file.Path))
+	}
+	cleaned := joinFiles(files, task.OutputPath)
+
+	// Check output size limit
+	if len(cleaned) > o.limits.MaxOutputSize {
+		return "", fmt.Errorf("output exceeds size limit: %d > %d", len(cleaned), o.limits.MaxOutputSize)
 	}
 
-	promptPath := filepath.Join(o.promptsPath, promptFile)
-	content, err := os.ReadFile(promptPath)
+	return cleaned, nil
+}
+
+// siblingSources returns the other Go files in the directory of filePath,
+// by slash-separated path: those among files, and those already generated in
+// the work directory that files doesn't replace
+func (o *Orchestrator) siblingSources(files []outputFile, filePath string) map[string]string {
+	dir := path.Dir(filePath)
+	siblings := make(map[string]string)
+	for _, file := range files {
+		if file.Path != filePath && path.Dir(file.Path) == dir {
+			siblings[file.Path] = file.Content
+		}
+	}
+
+	if o.workDir == "" {
+		return siblings
+	}
+	entries, err := os.ReadDir(filepath.Join(o.workDir, filepath.FromSlash(dir)))
 	if err != nil {
-		return "", fmt.Errorf("failed to read prompt file %s: %w", promptPath, err)
+		return siblings
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() || path.Ext(name) != ".go" || name == filePath {
+			continue
+		}
+		if _, ok := siblings[name]; ok {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(o.workDir, filepath.FromSlash(name)))
+		if err == nil {
+			siblings[name] = string(content)
+		}
+	}
+	return siblings
+}
+
+// loadPrompt reads and renders the prompt template declared by a task
+// Returns the system section (empty if the file has none) and the user prompt
+func (o *Orchestrator) loadPrompt(task Task) (system, user string, err error) {
+	if task.PromptFile == "" {
+		return "", "", fmt.Errorf("no prompt file for task %s", task.ID)
+	}
+
+	content, err := o.readPromptFile(task.PromptFile)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read prompt file %s: %w", task.PromptFile, err)
+	}
+
+	return o.renderPrompt(task, task.PromptFile, string(content))
+}
+
+// readPromptFile returns a prompt file from the override directory if it
+// exists there, otherwise from the embedded defaults
+func (o *Orchestrator) readPromptFile(name string) ([]byte, error) {
+	if o.promptsPath != "" {
+		content, err := os.ReadFile(filepath.Join(o.promptsPath, filepath.FromSlash(name)))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return content, err
+		}
 	}
 
-	return string(content), nil
+	if o.promptsFS == nil {
+		return nil, fs.ErrNotExist
+	}
+	return fs.ReadFile(o.promptsFS, path.Clean(filepath.ToSlash(name)))
 }
 
-// saveOutput writes generated code to the appropriate file
+// saveOutput writes generated code to the task's output files and records them
+// Output with "// file:" headers is split into one file per header
 func (o *Orchestrator) saveOutput(task Task, content string) error {
-	// Determine output file path based on task type
-	var outputPath string
-	switch task.Type {
-	case TaskGenerateModels:
-		outputPath = filepath.Join(o.workDir, "internal", "models", "todo.go")
-	case TaskGenerateHandlers:
-		outputPath = filepath.Join(o.workDir, "internal", "handlers", "todo_handler.go")
-	case TaskGenerateRepository:
-		outputPath = filepath.Join(o.workDir, "internal", "repository", "todo_repo.go")
-	case TaskGenerateTests:
-		outputPath = filepath.Join(o.workDir, "tests", "todo_handler_test.go")
-	default:
-		return fmt.Errorf("unknown task type for output: %s", task.Type)
+	if err := o.writeOutput(task, content); err != nil {
+		return err
 	}
 
-	// Create directory structure
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	// Store each file in the database
+	for _, file := range splitFiles(content, task.OutputPath) {
+		outputPath := filepath.Join(o.workDir, filepath.FromSlash(file.Path))
+		if err := o.storage.SaveGeneratedFile(task.ID, outputPath, file.Content, task.Model); err != nil {
+			return fmt.Errorf("failed to save file record: %w", err)
+		}
 	}
 
-	// Write file
-	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", outputPath, err)
+	return nil
+}
+
+// writeOutput writes content to the task's output files without recording them
+func (o *Orchestrator) writeOutput(task Task, content string) error {
+	files := splitFiles(content, task.OutputPath)
+
+	// Check every path before writing anything so a bad one leaves no partial output
+	if err := o.checkOutputPaths(task, files); err != nil {
+		return err
 	}
 
-	// Store in database
-	if err := o.storage.SaveGeneratedFile(task.ID, outputPath, content); err != nil {
-		return fmt.Errorf("failed to save file record: %w", err)
+	for _, file := range files {
+		outputPath := filepath.Join(o.workDir, filepath.FromSlash(file.Path))
+
+		// Create directory structure
+		dir := filepath.Dir(outputPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+
+		// Write file
+		if err := os.WriteFile(outputPath, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", outputPath, err)
+		}
 	}
+	return nil
+}
 
+// checkOutputPaths returns an error if any file is one the task may not write
+// Besides the checks on every output path, a task can't write the declared
+// output of another task in the run
+func (o *Orchestrator) checkOutputPaths(task Task, files []outputFile) error {
+	for _, file := range files {
+		if file.Path == task.OutputPath {
+			if err := validateRelativePath(file.Path); err != nil {
+				return fmt.Errorf("invalid output path for task %s: %w", task.ID, err)
+			}
+			continue
+		}
+		if err := checkOutputPath(file.Path, task.OutputPath); err != nil {
+			return fmt.Errorf("invalid output path for task %s: %w", task.ID, err)
+		}
+		for _, other := range o.tasks {
+			if other.ID != task.ID && path.Clean(other.OutputPath) == path.Clean(file.Path) {
+				return fmt.Errorf("invalid output path for task %s: %s is the output of task %s", task.ID, file.Path, other.ID)
+			}
+		}
+	}
 	return nil
 }
 
+// readOutput reads a task's previously generated files from the work directory
+// The stored output names the files; their content comes from disk so fixes
+// such as gofmt are included
+func (o *Orchestrator) readOutput(task Task) (string, error) {
+	files := o.taskFiles(task)
+	for i, file := range files {
+		outputPath := filepath.Join(o.workDir, filepath.FromSlash(file.Path))
+		content, err := os.ReadFile(outputPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read generated file %s: %w", outputPath, err)
+		}
+		files[i].Content = string(content)
+	}
+	return joinFiles(files, task.OutputPath), nil
+}
+
+// taskFiles returns the files of a task's stored output
+// A task without stored output has just its declared output file
+func (o *Orchestrator) taskFiles(task Task) []outputFile {
+	stored, err := o.storage.GetTask(task.ID)
+	if err != nil || strings.TrimSpace(stored.Output) == "" {
+		return []outputFile{{Path: task.OutputPath}}
+	}
+	return splitFiles(stored.Output, task.OutputPath)
+}
+
 // generateGoMod creates a go.mod file for the generated project
 func (o *Orchestrator) generateGoMod() error {
-	content := `module todo-api
+	content := `module ` + o.modulePath() + `
 
 go 1.21
 
@@ -348,8 +549,20 @@ require (
 	return os.WriteFile(outputPath, []byte(content), 0644)
 }
 
+// modulePath returns the module path for the generated project
+func (o *Orchestrator) modulePath() string {
+	if o.module == "" {
+		return "todo-api"
+	}
+	return o.module
+}
+
 // generateREADME creates a README file for the generated project
-func (o *Orchestrator) generateREADME() error {
+func (o *Orchestrator) generateREADME(p *Pipeline) error {
+	if p.Scaffold != ScaffoldTodoAPI {
+		return o.generatePipelineREADME(p)
+	}
+
 	content := `# Generated Todo API
 
 This REST API was automatically generated by the Overnight LLM PoC.
@@ -363,7 +576,7 @@ go mod download
 # Run tests
 go test ./...
 
-# Start the server
+# Start the server (PORT and DB_PATH default to 8080 and todos.db)
 go run cmd/server/main.go
 ` + "```" + `
 
@@ -388,24 +601,110 @@ Generated at: ` + time.Now().Format(time.RFC3339) + `
 	return os.WriteFile(outputPath, []byte(content), 0644)
 }
 
+// generatePipelineREADME creates a README listing the files of a custom pipeline
+func (o *Orchestrator) generatePipelineREADME(p *Pipeline) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", p.Name)
+	b.WriteString("This project was automatically generated by the Overnight LLM PoC.\n\n")
+	b.WriteString("## Generated Files\n\n")
+	for _, spec := range p.Tasks {
+		fmt.Fprintf(&b, "- %s - %s\n", spec.Output, spec.Input)
+	}
+	fmt.Fprintf(&b, "\nGenerated at: %s\n", time.Now().Format(time.RFC3339))
+
+	outputPath := filepath.Join(o.workDir, "README.md")
+	return os.WriteFile(outputPath, []byte(b.String()), 0644)
+}
+
 // generateServerMain creates the main.go entry point for the generated API
+// It wires the generated repository and handlers together, listens on $PORT
+// (default 8080) with the database at $DB_PATH (default todos.db), and shuts
+// down gracefully on SIGINT or SIGTERM
 func (o *Orchestrator) generateServerMain() error {
 	content := `package main
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"` + o.modulePath() + `/internal/handlers"
+	"` + o.modulePath() + `/internal/repository"
 )
 
 func main() {
-	fmt.Println("Starting Todo API server on :8080")
+	addr := ":" + getenv("PORT", "8080")
+
+	db, err := sql.Open("sqlite3", getenv("DB_PATH", "todos.db"))
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewTodoRepository(db)
+	if err := repo.CreateTable(); err != nil {
+		log.Fatalf("failed to create table: %v", err)
+	}
+	h := handlers.NewTodoHandler(repo)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/todos", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.ListTodos(w, r)
+		case http.MethodPost:
+			h.CreateTodo(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/todos/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetTodo(w, r)
+		case http.MethodPut:
+			h.UpdateTodo(w, r)
+		case http.MethodDelete:
+			h.DeleteTodo(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Starting Todo API server on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	}()
 
-	// Initialize database
-	// Setup routes
-	// Start server
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown failed: %v", err)
+	}
+	log.Println("Server stopped")
+}
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
 `
 	outputPath := filepath.Join(o.workDir, "cmd", "server", "main.go")
@@ -416,37 +715,33 @@ func main() {
 	return os.WriteFile(outputPath, []byte(content), 0644)
 }
 
-// validateGeneratedCode runs basic validation on generated Go code
-func (o *Orchestrator) validateGeneratedCode(ctx context.Context) error {
-	// For the PoC, we'll do basic file existence checks
-	// In a real implementation, would run go fmt, go vet, go build
-
-	requiredFiles := []string{
-		filepath.Join(o.workDir, "internal", "models", "todo.go"),
-		filepath.Join(o.workDir, "internal", "handlers", "todo_handler.go"),
-		filepath.Join(o.workDir, "internal", "repository", "todo_repo.go"),
-		filepath.Join(o.workDir, "go.mod"),
+// logError records an error for a specific task
+func (o *Orchestrator) logError(taskID string, err error) {
+	if err := o.storage.UpdateTaskError(taskID, err.Error()); err != nil {
+		fmt.Printf("Failed to log error for task %s: %v\n", taskID, err)
 	}
+}
 
-	for _, file := range requiredFiles {
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			return fmt.Errorf("required file missing: %s", file)
-		}
+// attemptSummary describes how many LLM calls a task needed, if more than one
+func (o *Orchestrator) attemptSummary(taskID string) string {
+	attempts, err := o.storage.GetAttempts(taskID)
+	if err != nil || len(attempts) <= 1 {
+		return ""
 	}
-
-	return nil
+	return fmt.Sprintf(" (%d LLM calls)", len(attempts))
 }
 
-// logError records an error for a specific task
-func (o *Orchestrator) logError(taskID string, err error) {
-	if err := o.storage.UpdateTaskError(taskID, err.Error()); err != nil {
-		fmt.Printf("Failed to log error for task %s: %v\n", taskID, err)
+// runTasks loads the tasks of the current run, or every task if no run has started
+func (o *Orchestrator) runTasks() ([]storage.Task, error) {
+	if o.runID == "" {
+		return o.storage.GetAllTasks()
 	}
+	return o.storage.GetRunTasks(o.runID)
 }
 
 // writeStatusFile creates a JSON status file for monitoring
 func (o *Orchestrator) writeStatusFile() error {
-	tasks, err := o.storage.GetAllTasks()
+	tasks, err := o.runTasks()
 	if err != nil {
 		return err
 	}
@@ -476,6 +771,7 @@ func (o *Orchestrator) writeStatusFile() error {
 		"timestamp": time.Now().Format(time.RFC3339),
 		"duration":  stats.TotalDuration.String(),
 		"workDir":   o.workDir,
+		"runID":     o.runID,
 	}
 
 	jsonData, err := json.MarshalIndent(statusData, "", "  ")
@@ -489,7 +785,7 @@ func (o *Orchestrator) writeStatusFile() error {
 
 // PrintSummary outputs a summary of the generation session
 func (o *Orchestrator) PrintSummary() {
-	tasks, err := o.storage.GetAllTasks()
+	tasks, err := o.runTasks()
 	if err != nil {
 		fmt.Printf("Failed to load tasks: %v\n", err)
 		return
@@ -510,13 +806,28 @@ func (o *Orchestrator) PrintSummary() {
 			status = "[FAIL]"
 			failed++
 		}
-		fmt.Printf("%s %s - %s\n", status, task.Type, task.Status)
+		model := ""
+		if task.Model != "" {
+			model = " [" + task.Model + "]"
+		}
+		fmt.Printf("%s %s - %s%s%s\n", status, task.Type, task.Status, model, o.attemptSummary(task.ID))
 	}
 
 	fmt.Printf("\nTotal Tasks: %d\n", len(tasks))
 	fmt.Printf("Completed:   %d\n", completed)
 	fmt.Printf("Failed:      %d\n", failed)
 	fmt.Printf("Duration:    %v\n", time.Since(o.startTime))
+	if results, err := o.storage.GetTestResults(o.runID); err == nil && len(results) > 0 {
+		counts := make(map[string]int)
+		for _, r := range results {
+			counts[r.Status]++
+		}
+		fmt.Printf("Tests:       %d passed, %d failed, %d skipped\n",
+			counts[validator.TestPass], counts[validator.TestFail], counts[validator.TestSkip])
+	}
+	if stats, ok := o.CacheStats(); ok {
+		fmt.Printf("Cache:       %d hit(s), %d miss(es)\n", stats.Hits, stats.Misses)
+	}
 	fmt.Printf("Output Dir:  %s\n", o.workDir)
 
 	if completed == len(tasks) {
@@ -7,9 +7,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"gorchestrator-poc/internal/llm"
 	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
 )
 
 // mockLLMProvider implements LLMProvider for testing
@@ -17,10 +20,12 @@ type mockLLMProvider struct {
 	completeFunc    func(ctx context.Context, prompt string) (string, error)
 	healthCheckFunc func(ctx context.Context) error
 	callCount       int
+	lastOptions     llm.Options
 }
 
-func (m *mockLLMProvider) Complete(ctx context.Context, prompt string) (string, error) {
+func (m *mockLLMProvider) Complete(ctx context.Context, prompt string, opts llm.Options) (string, error) {
 	m.callCount++
+	m.lastOptions = opts
 	if m.completeFunc != nil {
 		return m.completeFunc(ctx, prompt)
 	}
@@ -67,7 +72,7 @@ func TestNew(t *testing.T) {
 	}
 }
 
-// TestLoadPrompt verifies prompt loading for different task types
+// TestLoadPrompt verifies prompt loading for the default pipeline tasks
 func TestLoadPrompt(t *testing.T) {
 	// Create temp directory with prompt files
 	tempDir := t.TempDir()
@@ -75,16 +80,10 @@ func TestLoadPrompt(t *testing.T) {
 	os.MkdirAll(promptsDir, 0755)
 
 	// Create test prompt files
-	prompts := map[TaskType]string{
-		TaskGenerateModels:     "generate_models.txt",
-		TaskGenerateHandlers:   "generate_handlers.txt",
-		TaskGenerateRepository: "generate_repository.txt",
-		TaskGenerateTests:      "generate_tests.txt",
-	}
-
-	for taskType, filename := range prompts {
-		content := "Test prompt for " + string(taskType)
-		err := os.WriteFile(filepath.Join(promptsDir, filename), []byte(content), 0644)
+	tasks := DefaultPipeline().newTasks("run_test")
+	for _, task := range tasks {
+		content := "Test prompt for " + string(task.Type)
+		err := os.WriteFile(filepath.Join(promptsDir, task.PromptFile), []byte(content), 0644)
 		if err != nil {
 			t.Fatalf("Failed to create prompt file: %v", err)
 		}
@@ -96,22 +95,74 @@ func TestLoadPrompt(t *testing.T) {
 	}
 
 	// Test loading each prompt
-	for taskType := range prompts {
-		prompt, err := orch.loadPrompt(taskType)
+	for _, task := range tasks {
+		_, prompt, err := orch.loadPrompt(task)
 		if err != nil {
-			t.Errorf("Failed to load prompt for %s: %v", taskType, err)
+			t.Errorf("Failed to load prompt for %s: %v", task.Type, err)
 		}
 
-		expected := "Test prompt for " + string(taskType)
+		expected := "Test prompt for " + string(task.Type)
 		if prompt != expected {
 			t.Errorf("Expected prompt '%s', got '%s'", expected, prompt)
 		}
 	}
 
-	// Test unknown task type
-	_, err := orch.loadPrompt(TaskType("unknown"))
+	// Test task without a prompt file
+	_, _, err := orch.loadPrompt(Task{ID: "unknown"})
+	if err == nil {
+		t.Error("Expected error for task without prompt file, got nil")
+	}
+
+	// Test missing prompt file
+	_, _, err = orch.loadPrompt(Task{ID: "missing", PromptFile: "missing.txt"})
 	if err == nil {
-		t.Error("Expected error for unknown task type, got nil")
+		t.Error("Expected error for missing prompt file, got nil")
+	}
+}
+
+// TestPromptOverrides verifies override files win and missing ones fall back to the defaults
+func TestPromptOverrides(t *testing.T) {
+	overrides := t.TempDir()
+	os.WriteFile(filepath.Join(overrides, "handlers.txt"), []byte("custom handlers"), 0644)
+
+	orch := &Orchestrator{
+		promptsPath: overrides,
+		promptsFS: fstest.MapFS{
+			"models.txt":   {Data: []byte("default models")},
+			"handlers.txt": {Data: []byte("default handlers")},
+		},
+	}
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"handlers.txt", "custom handlers"},
+		{"models.txt", "default models"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, prompt, err := orch.loadPrompt(Task{ID: tt.file, PromptFile: tt.file})
+			if err != nil {
+				t.Fatalf("Failed to load prompt: %v", err)
+			}
+			if prompt != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, prompt)
+			}
+		})
+	}
+
+	// Without an override directory every file comes from the defaults
+	orch.SetPromptsDir("")
+	_, prompt, err := orch.loadPrompt(Task{ID: "handlers", PromptFile: "handlers.txt"})
+	if err != nil || prompt != "default handlers" {
+		t.Errorf("Expected default handlers prompt, got %q (%v)", prompt, err)
+	}
+
+	// Files in neither location are an error
+	if _, _, err := orch.loadPrompt(Task{ID: "tests", PromptFile: "tests.txt"}); err == nil {
+		t.Error("Expected error for prompt missing from both locations, got nil")
 	}
 }
 
@@ -134,25 +185,25 @@ func TestSaveOutput(t *testing.T) {
 	}{
 		{
 			name:     "models output",
-			task:     Task{ID: "1", Type: TaskGenerateModels},
+			task:     Task{ID: "1", Type: TaskGenerateModels, OutputPath: "internal/models/todo.go"},
 			content:  "package models\n\ntype Todo struct{}",
 			wantPath: filepath.Join(workDir, "internal", "models", "todo.go"),
 		},
 		{
 			name:     "handlers output",
-			task:     Task{ID: "2", Type: TaskGenerateHandlers},
+			task:     Task{ID: "2", Type: TaskGenerateHandlers, OutputPath: "internal/handlers/todo_handler.go"},
 			content:  "package handlers\n\nfunc ListTodos() {}",
 			wantPath: filepath.Join(workDir, "internal", "handlers", "todo_handler.go"),
 		},
 		{
 			name:     "repository output",
-			task:     Task{ID: "3", Type: TaskGenerateRepository},
+			task:     Task{ID: "3", Type: TaskGenerateRepository, OutputPath: "internal/repository/todo_repo.go"},
 			content:  "package repository\n\ntype TodoRepo struct{}",
 			wantPath: filepath.Join(workDir, "internal", "repository", "todo_repo.go"),
 		},
 		{
 			name:     "tests output",
-			task:     Task{ID: "4", Type: TaskGenerateTests},
+			task:     Task{ID: "4", Type: TaskGenerateTests, OutputPath: "tests/todo_handler_test.go"},
 			content:  "package tests\n\nfunc TestTodo(t *testing.T) {}",
 			wantPath: filepath.Join(workDir, "tests", "todo_handler_test.go"),
 		},
@@ -187,6 +238,12 @@ func TestSaveOutput(t *testing.T) {
 			}
 		})
 	}
+
+	// Output paths outside the work directory are rejected
+	err := orch.saveOutput(Task{ID: "5", OutputPath: "../escape.go"}, "package escape")
+	if err == nil {
+		t.Error("Expected error for output path outside work directory, got nil")
+	}
 }
 
 // TestGenerateGoMod verifies go.mod generation
@@ -222,36 +279,56 @@ func TestGenerateGoMod(t *testing.T) {
 	}
 }
 
-// TestValidateGeneratedCode verifies validation logic
-func TestValidateGeneratedCode(t *testing.T) {
+// TestGenerateServerMain verifies the entry point compiles against the
+// repository and handlers the prompts ask for
+func TestGenerateServerMain(t *testing.T) {
 	workDir := t.TempDir()
 	orch := &Orchestrator{workDir: workDir}
-
-	// Test with missing files
-	ctx := context.Background()
-	err := orch.validateGeneratedCode(ctx)
-	if err == nil {
-		t.Error("Expected error for missing files, got nil")
+	if err := orch.generateGoMod(); err != nil {
+		t.Fatalf("Failed to generate go.mod: %v", err)
 	}
-
-	// Create required files
-	requiredFiles := []string{
-		filepath.Join(workDir, "internal", "models", "todo.go"),
-		filepath.Join(workDir, "internal", "handlers", "todo_handler.go"),
-		filepath.Join(workDir, "internal", "repository", "todo_repo.go"),
-		filepath.Join(workDir, "go.mod"),
+	if err := orch.generateServerMain(); err != nil {
+		t.Fatalf("Failed to generate server main: %v", err)
 	}
 
-	for _, file := range requiredFiles {
-		dir := filepath.Dir(file)
-		os.MkdirAll(dir, 0755)
-		os.WriteFile(file, []byte("test content"), 0644)
-	}
+	overlay := map[string]string{
+		"internal/repository/todo_repo.go": `package repository
+
+import "database/sql"
 
-	// Now validation should pass
-	err = orch.validateGeneratedCode(ctx)
+type TodoRepository struct{ db *sql.DB }
+
+func NewTodoRepository(db *sql.DB) *TodoRepository { return &TodoRepository{db: db} }
+
+func (r *TodoRepository) CreateTable() error { return nil }
+`,
+		"internal/handlers/todo_handler.go": `package handlers
+
+import (
+	"net/http"
+
+	"todo-api/internal/repository"
+)
+
+type TodoHandler struct{ repo *repository.TodoRepository }
+
+func NewTodoHandler(repo *repository.TodoRepository) *TodoHandler { return &TodoHandler{repo: repo} }
+
+func (h *TodoHandler) ListTodos(w http.ResponseWriter, r *http.Request)  {}
+func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request)    {}
+func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {}
+func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {}
+func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {}
+`,
+	}
+	data, err := os.ReadFile(filepath.Join(workDir, "cmd", "server", "main.go"))
 	if err != nil {
-		t.Errorf("Unexpected validation error: %v", err)
+		t.Fatalf("Failed to read server main: %v", err)
+	}
+	overlay["cmd/server/main.go"] = string(data)
+
+	if diags := validator.NewChecker(workDir).Check(overlay); len(diags) > 0 {
+		t.Errorf("Expected the server main to compile, got %v", diags)
 	}
 }
 
@@ -289,10 +366,12 @@ func TestExecuteTask(t *testing.T) {
 
 	// Create and store task
 	task := Task{
-		ID:     "test-001",
-		Type:   TaskGenerateModels,
-		Input:  "test input",
-		Status: StatusPending,
+		ID:         "test-001",
+		Type:       TaskGenerateModels,
+		Input:      "test input",
+		PromptFile: "generate_models.txt",
+		OutputPath: "internal/models/todo.go",
+		Status:     StatusPending,
 	}
 
 	err := orch.storage.CreateTask(storage.Task{
@@ -368,8 +447,10 @@ func TestSafetyLimits(t *testing.T) {
 	}
 
 	task := Task{
-		ID:   "test-001",
-		Type: TaskGenerateModels,
+		ID:         "test-001",
+		Type:       TaskGenerateModels,
+		PromptFile: "generate_models.txt",
+		OutputPath: "internal/models/todo.go",
 	}
 
 	orch.storage.CreateTask(storage.Task{
@@ -0,0 +1,244 @@
+package orchestrator
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/validator"
+)
+
+// Embed the default Todo API pipeline at compile time
+// This keeps the built-in pipeline in the same format users write by hand
+//
+//go:embed todo_pipeline.json
+var todoPipelineJSON []byte
+
+// ScaffoldTodoAPI enables the Todo API server entry point and README
+const ScaffoldTodoAPI = "todo-api"
+
+// Pipeline describes a code generation run as a graph of tasks
+// Pipelines are loaded from JSON so new projects don't require a new binary
+type Pipeline struct {
+	Name     string            `json:"name"`
+	Module   string            `json:"module"`             // Module path written to the generated go.mod
+	Scaffold string            `json:"scaffold,omitempty"` // Optional built-in scaffolding (e.g. "todo-api")
+	Entities []string          `json:"entities,omitempty"` // Entity names available to prompt templates
+	Vars     map[string]string `json:"vars,omitempty"`     // Template variables; -var flags take precedence
+	Options  *llm.Options      `json:"options,omitempty"`  // Generation options for every task; override CLI flags
+	Models   []string          `json:"models,omitempty"`   // Model chain for every task; overrides -model
+	Tasks    []TaskSpec        `json:"tasks"`
+
+	// Validation stages run on the generated code; empty uses validator.DefaultStages
+	Validation []validator.Stage `json:"validation,omitempty"`
+}
+
+// TaskSpec declares a single task within a pipeline
+type TaskSpec struct {
+	ID         string       `json:"id"`
+	Type       TaskType     `json:"type,omitempty"`       // Defaults to the task ID
+	Input      string       `json:"input,omitempty"`      // Short description stored with the task
+	Prompt     string       `json:"prompt"`               // Prompt template (text/template), relative to the prompts directory
+	Output     string       `json:"output"`               // Output file, relative to the work directory
+	DependsOn  []string     `json:"depends_on,omitempty"` // IDs of tasks that must complete first
+	Context    string       `json:"context,omitempty"`    // Dependency context: "api" (default), "full" or "none"
+	Options    *llm.Options `json:"options,omitempty"`    // Generation options, layered over the pipeline's
+	Models     []string     `json:"models,omitempty"`     // Model chain, replacing the pipeline's
+	Candidates int          `json:"candidates,omitempty"` // Completions to choose from; overrides -candidates
+}
+
+// DefaultPipeline returns the built-in Todo REST API pipeline
+func DefaultPipeline() *Pipeline {
+	p, err := ParsePipeline(todoPipelineJSON)
+	if err != nil {
+		// The embedded pipeline is covered by tests, so this is a programming error
+		panic(fmt.Sprintf("invalid embedded pipeline: %v", err))
+	}
+	return p
+}
+
+// LoadPipeline reads and validates a pipeline definition from a JSON file
+func LoadPipeline(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline file %s: %w", path, err)
+	}
+
+	p, err := ParsePipeline(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParsePipeline decodes and validates a JSON pipeline definition
+// Unknown fields are rejected so typos don't silently change behaviour
+func ParsePipeline(data []byte) (*Pipeline, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var p Pipeline
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to decode pipeline: %w", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks that the pipeline is complete and internally consistent
+func (p *Pipeline) Validate() error {
+	if len(p.Tasks) == 0 {
+		return fmt.Errorf("pipeline has no tasks")
+	}
+	if p.Options != nil {
+		if err := p.Options.Validate(); err != nil {
+			return fmt.Errorf("pipeline has invalid options: %w", err)
+		}
+	}
+	if err := validateModels(p.Models); err != nil {
+		return fmt.Errorf("pipeline has invalid models: %w", err)
+	}
+	if err := validator.ValidateStages(p.Validation); err != nil {
+		return fmt.Errorf("pipeline has invalid validation: %w", err)
+	}
+
+	seen := make(map[string]bool, len(p.Tasks))
+	ids := make([]string, 0, len(p.Tasks))
+	deps := make(map[string][]string, len(p.Tasks))
+	for i, spec := range p.Tasks {
+		if spec.ID == "" {
+			return fmt.Errorf("task %d has no id", i+1)
+		}
+		if seen[spec.ID] {
+			return fmt.Errorf("duplicate task id: %s", spec.ID)
+		}
+		if spec.Prompt == "" {
+			return fmt.Errorf("task %s has no prompt", spec.ID)
+		}
+		if err := validateRelativePath(spec.Prompt); err != nil {
+			return fmt.Errorf("task %s has invalid prompt: %w", spec.ID, err)
+		}
+		if err := validateRelativePath(spec.Output); err != nil {
+			return fmt.Errorf("task %s has invalid output: %w", spec.ID, err)
+		}
+		if spec.Options != nil {
+			if err := spec.Options.Validate(); err != nil {
+				return fmt.Errorf("task %s has invalid options: %w", spec.ID, err)
+			}
+		}
+		if err := validateModels(spec.Models); err != nil {
+			return fmt.Errorf("task %s has invalid models: %w", spec.ID, err)
+		}
+		if spec.Candidates < 0 {
+			return fmt.Errorf("task %s has negative candidates: %d", spec.ID, spec.Candidates)
+		}
+		switch spec.Context {
+		case "", ContextAPI, ContextFull, ContextNone:
+		default:
+			return fmt.Errorf("task %s has unknown context mode: %s", spec.ID, spec.Context)
+		}
+
+		seen[spec.ID] = true
+		ids = append(ids, spec.ID)
+		deps[spec.ID] = spec.DependsOn
+	}
+
+	// Dependencies must exist and must not form a cycle
+	if _, err := topoOrder(ids, deps); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newTasks builds the runnable tasks for a pipeline with IDs unique to the run
+func (p *Pipeline) newTasks(runID string) []Task {
+	base := llm.DefaultOptions()
+	if p.Options != nil {
+		base = base.Merge(*p.Options)
+	}
+
+	tasks := make([]Task, 0, len(p.Tasks))
+	for _, spec := range p.Tasks {
+		taskType := spec.Type
+		if taskType == "" {
+			taskType = TaskType(spec.ID)
+		}
+
+		contextMode := spec.Context
+		if contextMode == "" {
+			contextMode = ContextAPI
+		}
+
+		// Dependencies refer to run-scoped task IDs so the scheduler can match them
+		var dependsOn []string
+		for _, dep := range spec.DependsOn {
+			dependsOn = append(dependsOn, fmt.Sprintf("%s_%s", runID, dep))
+		}
+
+		options := base
+		if spec.Options != nil {
+			options = base.Merge(*spec.Options)
+		}
+
+		models := p.Models
+		if len(spec.Models) > 0 {
+			models = spec.Models
+		}
+
+		tasks = append(tasks, Task{
+			ID:          fmt.Sprintf("%s_%s", runID, spec.ID),
+			Name:        spec.ID,
+			Type:        taskType,
+			Input:       spec.Input,
+			PromptFile:  spec.Prompt,
+			OutputPath:  spec.Output,
+			DependsOn:   dependsOn,
+			ContextMode: contextMode,
+			Options:     options,
+			Models:      models,
+			Candidates:  spec.Candidates,
+			Status:      StatusPending,
+		})
+	}
+	return tasks
+}
+
+// validateModels rejects empty and repeated entries in a model chain
+func validateModels(models []string) error {
+	seen := make(map[string]bool, len(models))
+	for _, model := range models {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("model name is empty")
+		}
+		if seen[model] {
+			return fmt.Errorf("model listed twice: %s", model)
+		}
+		seen[model] = true
+	}
+	return nil
+}
+
+// validateRelativePath ensures a path stays inside the directory it is joined to
+func validateRelativePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is empty")
+	}
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path must be relative: %s", path)
+	}
+
+	cleaned := filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("path escapes the output directory: %s", path)
+	}
+	return nil
+}
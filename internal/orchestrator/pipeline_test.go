@@ -0,0 +1,291 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// TestDefaultPipeline verifies the embedded Todo API pipeline
+func TestDefaultPipeline(t *testing.T) {
+	p := DefaultPipeline()
+
+	if p.Module != "todo-api" {
+		t.Errorf("Expected module todo-api, got %s", p.Module)
+	}
+
+	if p.Scaffold != ScaffoldTodoAPI {
+		t.Errorf("Expected scaffold %s, got %s", ScaffoldTodoAPI, p.Scaffold)
+	}
+
+	wantTypes := []TaskType{
+		TaskGenerateModels,
+		TaskGenerateHandlers,
+		TaskGenerateRepository,
+		TaskGenerateTests,
+	}
+	if len(p.Tasks) != len(wantTypes) {
+		t.Fatalf("Expected %d tasks, got %d", len(wantTypes), len(p.Tasks))
+	}
+
+	for i, spec := range p.Tasks {
+		if spec.Type != wantTypes[i] {
+			t.Errorf("Task %d: expected type %s, got %s", i, wantTypes[i], spec.Type)
+		}
+	}
+}
+
+// TestParsePipeline verifies pipeline decoding and validation errors
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		name          string
+		json          string
+		wantError     bool
+		errorContains string
+	}{
+		{
+			name: "valid pipeline",
+			json: `{"name": "cli", "module": "example.com/cli", "tasks": [
+				{"id": "main", "prompt": "main.txt", "output": "main.go"},
+				{"id": "tests", "prompt": "tests.txt", "output": "main_test.go", "depends_on": ["main"]}
+			]}`,
+		},
+		{
+			name:          "no tasks",
+			json:          `{"name": "empty", "tasks": []}`,
+			wantError:     true,
+			errorContains: "no tasks",
+		},
+		{
+			name:          "unknown field",
+			json:          `{"name": "typo", "taks": []}`,
+			wantError:     true,
+			errorContains: "unknown field",
+		},
+		{
+			name: "duplicate id",
+			json: `{"tasks": [
+				{"id": "a", "prompt": "a.txt", "output": "a.go"},
+				{"id": "a", "prompt": "b.txt", "output": "b.go"}
+			]}`,
+			wantError:     true,
+			errorContains: "duplicate task id",
+		},
+		{
+			name: "duplicate model",
+			json: `{"tasks": [
+				{"id": "a", "prompt": "a.txt", "output": "a.go", "models": ["codellama:7b", "codellama:7b"]}
+			]}`,
+			wantError:     true,
+			errorContains: "model listed twice",
+		},
+		{
+			name:          "missing prompt",
+			json:          `{"tasks": [{"id": "a", "output": "a.go"}]}`,
+			wantError:     true,
+			errorContains: "no prompt",
+		},
+		{
+			name:          "output escapes work directory",
+			json:          `{"tasks": [{"id": "a", "prompt": "a.txt", "output": "../../etc/passwd"}]}`,
+			wantError:     true,
+			errorContains: "escapes",
+		},
+		{
+			name:          "absolute output",
+			json:          `{"tasks": [{"id": "a", "prompt": "a.txt", "output": "/tmp/a.go"}]}`,
+			wantError:     true,
+			errorContains: "must be relative",
+		},
+		{
+			name:          "invalid pipeline options",
+			json:          `{"options": {"top_p": 2}, "tasks": [{"id": "a", "prompt": "a.txt", "output": "a.go"}]}`,
+			wantError:     true,
+			errorContains: "pipeline has invalid options",
+		},
+		{
+			name:          "invalid task options",
+			json:          `{"tasks": [{"id": "a", "prompt": "a.txt", "output": "a.go", "options": {"temperature": -1}}]}`,
+			wantError:     true,
+			errorContains: "task a has invalid options",
+		},
+		{
+			name: "unknown dependency",
+			json: `{"tasks": [
+				{"id": "a", "prompt": "a.txt", "output": "a.go", "depends_on": ["missing"]}
+			]}`,
+			wantError:     true,
+			errorContains: "depends on unknown task missing",
+		},
+		{
+			name: "dependency declared later",
+			json: `{"tasks": [
+				{"id": "b", "prompt": "b.txt", "output": "b.go", "depends_on": ["a"]},
+				{"id": "a", "prompt": "a.txt", "output": "a.go"}
+			]}`,
+		},
+		{
+			name: "validation stages",
+			json: `{"tasks": [{"id": "a", "prompt": "a.txt", "output": "a.go"}], "validation": [
+				{"name": "build", "required": true},
+				{"name": "test", "timeout": "5m"},
+				{"name": "lint", "command": ["staticcheck", "./..."]}
+			]}`,
+		},
+		{
+			name: "invalid validation stage",
+			json: `{"tasks": [{"id": "a", "prompt": "a.txt", "output": "a.go"}], "validation": [
+				{"name": "lint"}
+			]}`,
+			wantError:     true,
+			errorContains: "pipeline has invalid validation",
+		},
+		{
+			name: "dependency cycle",
+			json: `{"tasks": [
+				{"id": "a", "prompt": "a.txt", "output": "a.go", "depends_on": ["b"]},
+				{"id": "b", "prompt": "b.txt", "output": "b.go", "depends_on": ["a"]}
+			]}`,
+			wantError:     true,
+			errorContains: "dependency cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePipeline([]byte(tt.json))
+
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing '%s', got '%s'", tt.errorContains, err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestLoadPipeline verifies loading a pipeline from disk
+func TestLoadPipeline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.json")
+	content := `{"name": "cli", "module": "example.com/cli", "tasks": [
+		{"id": "main", "input": "entry point", "prompt": "main.txt", "output": "cmd/cli/main.go"}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write pipeline: %v", err)
+	}
+
+	p, err := LoadPipeline(path)
+	if err != nil {
+		t.Fatalf("Failed to load pipeline: %v", err)
+	}
+
+	if p.Name != "cli" || p.Module != "example.com/cli" {
+		t.Errorf("Unexpected pipeline header: %+v", p)
+	}
+
+	// Missing files are reported with their path
+	_, err = LoadPipeline(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("Expected error mentioning missing.json, got %v", err)
+	}
+}
+
+// TestNewTasks verifies runnable tasks are built from pipeline specs
+func TestNewTasks(t *testing.T) {
+	p := &Pipeline{Tasks: []TaskSpec{
+		{ID: "main", Prompt: "main.txt", Output: "main.go"},
+		{ID: "tests", Type: TaskGenerateTests, Prompt: "tests.txt", Output: "main_test.go", DependsOn: []string{"main"}},
+	}}
+
+	tasks := p.newTasks("run_1")
+
+	if tasks[0].ID != "run_1_main" {
+		t.Errorf("Expected ID run_1_main, got %s", tasks[0].ID)
+	}
+
+	// Type defaults to the task ID when not declared
+	if tasks[0].Type != TaskType("main") {
+		t.Errorf("Expected type main, got %s", tasks[0].Type)
+	}
+
+	if tasks[1].Type != TaskGenerateTests {
+		t.Errorf("Expected type %s, got %s", TaskGenerateTests, tasks[1].Type)
+	}
+
+	if tasks[1].PromptFile != "tests.txt" || tasks[1].OutputPath != "main_test.go" {
+		t.Errorf("Prompt or output not copied: %+v", tasks[1])
+	}
+
+	if len(tasks[1].DependsOn) != 1 || tasks[1].DependsOn[0] != "run_1_main" {
+		t.Errorf("Expected dependency on run_1_main, got %v", tasks[1].DependsOn)
+	}
+
+	if tasks[1].Status != StatusPending {
+		t.Errorf("Expected status %s, got %s", StatusPending, tasks[1].Status)
+	}
+}
+
+// TestTaskOptions verifies task options are layered over pipeline options and defaults
+func TestTaskOptions(t *testing.T) {
+	p, err := ParsePipeline([]byte(`{
+		"options": {"num_ctx": 8192},
+		"tasks": [
+			{"id": "models", "prompt": "m.txt", "output": "m.go"},
+			{"id": "tests", "prompt": "t.txt", "output": "t_test.go",
+			 "options": {"temperature": 0.7, "num_predict": 8192, "seed": 1}}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse pipeline: %v", err)
+	}
+
+	tasks := p.newTasks("run_1")
+	models, tests := tasks[0].Options, tasks[1].Options
+
+	if *models.Temperature != 0.2 || models.NumPredict != 4096 || models.NumCtx != 8192 || models.Seed != nil {
+		t.Errorf("Unexpected models options: %+v", models)
+	}
+	if *tests.Temperature != 0.7 || tests.NumPredict != 8192 || tests.NumCtx != 8192 || *tests.Seed != 1 {
+		t.Errorf("Unexpected tests options: %+v", tests)
+	}
+
+	// Command line options sit below the pipeline's own
+	cli := llm.DefaultOptions().Merge(llm.Options{Temperature: llm.Float(0.5), NumCtx: 4096})
+	merged := p.withOptions(cli).newTasks("run_1")
+	if *merged[0].Options.Temperature != 0.5 || merged[0].Options.NumCtx != 8192 {
+		t.Errorf("Unexpected options with command line overrides: %+v", merged[0].Options)
+	}
+	if *merged[1].Options.Temperature != 0.7 {
+		t.Errorf("Task options should win over the command line, got %v", *merged[1].Options.Temperature)
+	}
+}
+
+// TestTaskModels verifies task model chains replace the pipeline's
+func TestTaskModels(t *testing.T) {
+	p, err := ParsePipeline([]byte(`{
+		"models": ["codellama:7b", "codellama:13b"],
+		"tasks": [
+			{"id": "models", "prompt": "m.txt", "output": "m.go"},
+			{"id": "tests", "prompt": "t.txt", "output": "t_test.go", "models": ["openai:gpt-4o-mini"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse pipeline: %v", err)
+	}
+
+	tasks := p.newTasks("run_1")
+	if strings.Join(tasks[0].Models, ",") != "codellama:7b,codellama:13b" {
+		t.Errorf("Expected pipeline models, got %v", tasks[0].Models)
+	}
+	if strings.Join(tasks[1].Models, ",") != "openai:gpt-4o-mini" {
+		t.Errorf("Expected task models, got %v", tasks[1].Models)
+	}
+}
@@ -0,0 +1,210 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/validator"
+)
+
+// CodeValidator checks generated code with the Go toolchain
+// Implemented by validator.Validator; tests substitute fakes
+type CodeValidator interface {
+	ValidateAll(ctx context.Context) []validator.ValidationResult
+}
+
+// SetValidator enables the compile-error repair loop using the given validator
+func (o *Orchestrator) SetValidator(v CodeValidator) {
+	o.validator = v
+}
+
+// repairGeneratedCode re-prompts the LLM with toolchain errors until the code
+// builds cleanly. A task gets MaxRetries repair rounds per model; once they are
+// used up it is regenerated with the next model in its chain, if any
+func (o *Orchestrator) repairGeneratedCode(ctx context.Context, tasks []Task) error {
+	// Each task's repair conversation carries over between rounds
+	conversations := make(map[string][]llm.Message)
+	rounds := make(map[string]int)
+
+	for round := 1; ; round++ {
+		diags := o.collectDiagnostics(ctx)
+		if len(diags) == 0 {
+			if round > 1 {
+				fmt.Printf("[DONE] Repaired generated code in %d round(s)\n", round-1)
+			}
+			return nil
+		}
+
+		// Group diagnostics by the task of this run that produced the offending file
+		inRun := make(map[string]bool, len(tasks))
+		for _, task := range tasks {
+			inRun[task.ID] = true
+		}
+		byTask := make(map[string][]validator.Diagnostic)
+		for _, d := range diags {
+			if inRun[d.TaskID] {
+				byTask[d.TaskID] = append(byTask[d.TaskID], d)
+			}
+		}
+		if len(byTask) == 0 {
+			return fmt.Errorf("%d error(s) in files not produced by any task", len(diags))
+		}
+
+		fmt.Printf("\nRepair round %d: %d error(s) in %d file(s)\n", round, len(diags), len(byTask))
+		progressed := false
+		for _, task := range tasks {
+			taskDiags, ok := byTask[task.ID]
+			if !ok {
+				continue
+			}
+			task = o.withStoredModel(task)
+
+			// Out of repair rounds with this model: start over with the next one
+			if rounds[task.ID] >= o.limits.MaxRetries {
+				reason := fmt.Sprintf("%d error(s) remain after %d repair round(s)", len(taskDiags), rounds[task.ID])
+				escalated, err := o.escalateTask(ctx, task, reason)
+				if err != nil {
+					return fmt.Errorf("failed to regenerate %s: %w", task.OutputPath, err)
+				}
+				if escalated {
+					delete(conversations, task.ID)
+					rounds[task.ID] = 0
+					progressed = true
+				}
+				continue
+			}
+
+			conversation, err := o.repairTask(ctx, task, taskDiags, conversations[task.ID])
+			if err != nil {
+				return fmt.Errorf("failed to repair %s: %w", task.OutputPath, err)
+			}
+			conversations[task.ID] = conversation
+			rounds[task.ID]++
+			progressed = true
+		}
+
+		if !progressed {
+			return fmt.Errorf("%d error(s) remain after %d repair round(s)", len(diags), o.limits.MaxRetries)
+		}
+	}
+}
+
+// collectDiagnostics runs the validator and returns deduplicated build and vet
+// errors, attributed to the tasks that generated their files
+func (o *Orchestrator) collectDiagnostics(ctx context.Context) []validator.Diagnostic {
+	seen := make(map[string]bool)
+	var diags []validator.Diagnostic
+
+	for _, result := range o.validator.ValidateAll(ctx) {
+		// Formatting is fixed automatically, so only compiler and vet output matters
+		if result.Success || result.Tool == "gofmt" {
+			continue
+		}
+
+		// Validators that only report raw output are parsed here
+		found := result.Diagnostics
+		if found == nil {
+			found = validator.ParseDiagnostics(result.Tool, result.Output, o.workDir)
+		}
+
+		for _, d := range found {
+			key := fmt.Sprintf("%s:%d:%d:%s", d.File, d.Line, d.Column, d.Message)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			diags = append(diags, d)
+		}
+	}
+
+	o.AttributeDiagnostics(diags)
+	return diags
+}
+
+// AttributeDiagnostics sets the task that generated each diagnostic's file,
+// as recorded in files_generated. Diagnostics in files no task generated,
+// such as scaffolding, are left unattributed
+func (o *Orchestrator) AttributeDiagnostics(diags []validator.Diagnostic) {
+	if len(diags) == 0 {
+		return
+	}
+
+	owners, err := o.storage.GetFileTasks()
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return
+	}
+	for i := range diags {
+		diags[i].TaskID = owners[filepath.Join(o.workDir, filepath.FromSlash(diags[i].File))]
+	}
+}
+
+// repairTask asks the LLM to fix a task's files and returns the extended
+// conversation. The first round starts from the original prompt with the
+// current code as the model's reply; later rounds only add the new errors.
+// Files of a multi-file task that the reply leaves out are kept as they were
+func (o *Orchestrator) repairTask(ctx context.Context, task Task, diags []validator.Diagnostic, conversation []llm.Message) ([]llm.Message, error) {
+	if len(conversation) == 0 {
+		messages, err := o.buildPrompt(task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt: %w", err)
+		}
+
+		code, err := o.readOutput(task)
+		if err != nil {
+			return nil, err
+		}
+		conversation = append(messages, llm.Message{Role: llm.RoleAssistant, Content: code})
+	}
+	files := o.taskFiles(task)
+	conversation = append(conversation, llm.Message{Role: llm.RoleUser, Content: buildRepairPrompt(filePaths(files), diags)})
+
+	fmt.Printf("  → Repairing %s (%d error(s))...\n", task.OutputPath, len(diags))
+	reply, err := o.generateWithRetry(ctx, task, phaseRepair, conversation)
+	if err != nil {
+		return nil, err
+	}
+	fixed := joinFiles(mergeFiles(files, splitFiles(reply, task.OutputPath)), task.OutputPath)
+
+	if err := o.saveOutput(task, fixed); err != nil {
+		return nil, fmt.Errorf("failed to save output: %w", err)
+	}
+
+	if err := o.storage.UpdateTaskOutput(task.ID, fixed); err != nil {
+		return nil, fmt.Errorf("failed to save task output: %w", err)
+	}
+
+	return append(conversation, llm.Message{Role: llm.RoleAssistant, Content: fixed}), nil
+}
+
+// buildRepairPrompt asks the model to fix the errors reported for its last reply
+// Errors are labelled with their file when the reply spanned several files
+func buildRepairPrompt(paths []string, diags []validator.Diagnostic) string {
+	multi := len(paths) > 1
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The code you generated for %s does not compile.", strings.Join(paths, ", "))
+	b.WriteString(" The Go toolchain reported these errors:\n")
+	for _, d := range diags {
+		b.WriteString("- ")
+		if multi {
+			fmt.Fprintf(&b, "%s ", d.File)
+		}
+		if d.Column > 0 {
+			fmt.Fprintf(&b, "line %d, column %d: %s\n", d.Line, d.Column, d.Message)
+		} else {
+			fmt.Fprintf(&b, "line %d: %s\n", d.Line, d.Message)
+		}
+	}
+	if multi {
+		b.WriteString("\nFix every error and output each file that needs changes in full,")
+		b.WriteString(` each starting with a "// file: <path>" line.`)
+	} else {
+		b.WriteString("\nFix every error and output the complete corrected file.")
+	}
+	b.WriteString(" Output ONLY the complete, compilable Go code. No explanations or markdown.")
+	return b.String()
+}
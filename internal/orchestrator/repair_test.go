@@ -0,0 +1,235 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+)
+
+// fakeValidator returns a scripted sequence of validation results
+type fakeValidator struct {
+	rounds [][]validator.ValidationResult
+	calls  int
+}
+
+func (f *fakeValidator) ValidateAll(ctx context.Context) []validator.ValidationResult {
+	f.calls++
+	if f.calls > len(f.rounds) {
+		return f.rounds[len(f.rounds)-1]
+	}
+	return f.rounds[f.calls-1]
+}
+
+// TestRepairGeneratedCode verifies failing files are regenerated with their errors
+func TestRepairGeneratedCode(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	promptsDir := filepath.Join(workDir, "prompts")
+	os.MkdirAll(promptsDir, 0755)
+	os.WriteFile(filepath.Join(promptsDir, "generate_models.txt"), []byte("models prompt"), 0644)
+	os.WriteFile(filepath.Join(promptsDir, "generate_handlers.txt"), []byte("handlers prompt"), 0644)
+
+	var prompts []string
+	mockLLM := &mockLLMProvider{
+		completeFunc: func(ctx context.Context, prompt string) (string, error) {
+			prompts = append(prompts, prompt)
+			return "package models\n\nimport \"errors\"\n\nvar _ = errors.New\n", nil
+		},
+	}
+
+	fake := &fakeValidator{rounds: [][]validator.ValidationResult{
+		{
+			{Tool: "gofmt", Success: false, Output: "Files need formatting:\nx.go"},
+			{Tool: "go build", Success: false, Output: "# todo-api/internal/models\ninternal/models/todo.go:5:25: undefined: errors"},
+		},
+		{
+			{Tool: "go build", Success: true},
+		},
+	}}
+
+	orch := &Orchestrator{
+		llm:         mockLLM,
+		storage:     storage.NewStorage(db),
+		workDir:     workDir,
+		promptsPath: promptsDir,
+		validator:   fake,
+		limits: SafetyLimits{
+			MaxRetries:    3,
+			MaxOutputSize: 1024 * 1024,
+		},
+	}
+
+	tasks := []Task{
+		{ID: "run_1_models", PromptFile: "generate_models.txt", OutputPath: "internal/models/todo.go"},
+		{ID: "run_1_handlers", PromptFile: "generate_handlers.txt", OutputPath: "internal/handlers/todo_handler.go"},
+	}
+	for _, task := range tasks {
+		orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusComplete)})
+		orch.saveOutput(task, "package models\n\nvar _ = errors.New")
+	}
+
+	if err := orch.repairGeneratedCode(context.Background(), tasks); err != nil {
+		t.Fatalf("Unexpected repair error: %v", err)
+	}
+
+	// Only the file with errors is regenerated
+	if len(prompts) != 1 {
+		t.Fatalf("Expected 1 repair prompt, got %d", len(prompts))
+	}
+
+	for _, want := range []string{"models prompt", "var _ = errors.New", "line 5, column 25: undefined: errors"} {
+		if !strings.Contains(prompts[0], want) {
+			t.Errorf("Repair prompt missing %q:\n%s", want, prompts[0])
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(workDir, "internal", "models", "todo.go"))
+	if !strings.Contains(string(data), `import "errors"`) {
+		t.Errorf("Repaired file not written, got:\n%s", data)
+	}
+
+	stored, _ := orch.storage.GetTask("run_1_models")
+	if !strings.Contains(stored.Output, `import "errors"`) {
+		t.Errorf("Repaired output not stored, got:\n%s", stored.Output)
+	}
+}
+
+// TestRepairGivesUpAfterMaxRetries verifies the loop is bounded by MaxRetries
+func TestRepairGivesUpAfterMaxRetries(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "prompt.txt"), []byte("prompt"), 0644)
+
+	mockLLM := &mockLLMProvider{}
+	fake := &fakeValidator{rounds: [][]validator.ValidationResult{
+		{{Tool: "go vet", Success: false, Output: "vet: main.go:3:1: unreachable code"}},
+	}}
+
+	orch := &Orchestrator{
+		llm:         mockLLM,
+		storage:     storage.NewStorage(db),
+		workDir:     workDir,
+		promptsPath: workDir,
+		validator:   fake,
+		limits: SafetyLimits{
+			MaxRetries:    2,
+			MaxOutputSize: 1024,
+		},
+	}
+
+	task := Task{ID: "run_1_main", PromptFile: "prompt.txt", OutputPath: "main.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusComplete)})
+	orch.saveOutput(task, "package main")
+
+	err := orch.repairGeneratedCode(context.Background(), []Task{task})
+	if err == nil || !strings.Contains(err.Error(), "after 2 repair round(s)") {
+		t.Fatalf("Expected error after 2 repair rounds, got %v", err)
+	}
+
+	if mockLLM.callCount != 2 {
+		t.Errorf("Expected 2 repair calls, got %d", mockLLM.callCount)
+	}
+}
+
+// TestRepairConversation verifies later repair rounds continue the same conversation
+func TestRepairConversation(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "models.txt"), []byte("{{define \"system\"}}You write Go.{{end}}models prompt"), 0644)
+
+	provider := &mockChatProvider{reply: func(call int) string {
+		return fmt.Sprintf("package models\n\n// attempt %d\n", call)
+	}}
+
+	fake := &fakeValidator{rounds: [][]validator.ValidationResult{
+		{{Tool: "go build", Output: "internal/models/todo.go:3:1: undefined: errors"}},
+		{{Tool: "go build", Output: "internal/models/todo.go:4:1: missing return"}},
+		{{Tool: "go build", Success: true}},
+	}}
+
+	orch := &Orchestrator{
+		llm:         provider,
+		storage:     storage.NewStorage(db),
+		workDir:     workDir,
+		promptsPath: workDir,
+		validator:   fake,
+		limits:      SafetyLimits{MaxRetries: 3, MaxOutputSize: 1024 * 1024},
+	}
+
+	task := Task{ID: "run_1_models", PromptFile: "models.txt", OutputPath: "internal/models/todo.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusComplete)})
+	orch.saveOutput(task, "package models\n\nvar _ = errors.New")
+
+	if err := orch.repairGeneratedCode(context.Background(), []Task{task}); err != nil {
+		t.Fatalf("Unexpected repair error: %v", err)
+	}
+
+	if len(provider.conversations) != 2 {
+		t.Fatalf("Expected 2 repair calls, got %d", len(provider.conversations))
+	}
+
+	// Round one: system, prompt, original code, errors
+	first := provider.conversations[0]
+	roles := []string{llm.RoleSystem, llm.RoleUser, llm.RoleAssistant, llm.RoleUser}
+	if len(first) != len(roles) {
+		t.Fatalf("Expected %d messages in round one, got %d", len(roles), len(first))
+	}
+	for i, role := range roles {
+		if first[i].Role != role {
+			t.Errorf("Message %d: expected role %s, got %s", i, role, first[i].Role)
+		}
+	}
+	if first[2].Content != "package models\n\nvar _ = errors.New" || !strings.Contains(first[3].Content, "undefined: errors") {
+		t.Errorf("Unexpected round one conversation: %+v", first)
+	}
+
+	// Round two appends the first fix and the new errors instead of starting over
+	second := provider.conversations[1]
+	if len(second) != 6 {
+		t.Fatalf("Expected 6 messages in round two, got %d", len(second))
+	}
+	if !strings.Contains(second[4].Content, "attempt 1") || !strings.Contains(second[5].Content, "missing return") {
+		t.Errorf("Unexpected round two conversation: %+v", second[4:])
+	}
+	if strings.Contains(second[5].Content, "undefined: errors") {
+		t.Error("Round two should only report the new errors")
+	}
+}
+
+// TestAttributeDiagnostics verifies diagnostics are tied to the task that last wrote their file
+func TestAttributeDiagnostics(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	orch := &Orchestrator{storage: storage.NewStorage(db), workDir: t.TempDir()}
+	for _, id := range []string{"run_1_models", "run_2_models"} {
+		orch.storage.CreateTask(storage.Task{ID: id, Type: "test", Status: string(StatusComplete)})
+		orch.saveOutput(Task{ID: id, OutputPath: "internal/models/todo.go"}, "package models")
+	}
+
+	diags := []validator.Diagnostic{
+		{File: "internal/models/todo.go", Line: 1, Message: "undefined: errors"},
+		{File: "main.go", Line: 1, Message: "undefined: handlers"},
+	}
+	orch.AttributeDiagnostics(diags)
+
+	if diags[0].TaskID != "run_2_models" {
+		t.Errorf("Expected the latest task to own the file, got %q", diags[0].TaskID)
+	}
+	if diags[1].TaskID != "" {
+		t.Errorf("Expected scaffolding to be unattributed, got %q", diags[1].TaskID)
+	}
+}
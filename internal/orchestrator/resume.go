@@ -0,0 +1,167 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorchestrator-poc/internal/storage"
+)
+
+// Resume continues an interrupted run from the state stored in the database
+// Completed tasks are skipped and their files restored from files_generated;
+// pending, running and failed tasks are executed again. A run resumed in
+// another work directory moves there: restored files are recorded under it
+func (o *Orchestrator) Resume(ctx context.Context, runID string) error {
+	o.startTime = time.Now()
+
+	run, err := o.storage.GetRun(runID)
+	if err != nil {
+		return err
+	}
+
+	p, err := ParsePipeline([]byte(run.Pipeline))
+	if err != nil {
+		return fmt.Errorf("stored pipeline for %s is invalid: %w", runID, err)
+	}
+
+	if err := os.MkdirAll(o.workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	o.runID = runID
+	o.project = run.Project
+	fmt.Printf("Resuming run: %s\n", runID)
+	relocated := !sameDir(run.WorkDir, o.workDir)
+	if relocated {
+		fmt.Printf("WARNING: Moving run from %s to %s\n", run.WorkDir, o.workDir)
+	}
+	fmt.Println()
+
+	stored, err := o.storage.GetRunTasks(runID)
+	if err != nil {
+		return fmt.Errorf("failed to load tasks for %s: %w", runID, err)
+	}
+	byID := make(map[string]storage.Task, len(stored))
+	for _, task := range stored {
+		byID[task.ID] = task
+	}
+
+	// Restore finished work and reset everything else to pending
+	tasks := p.newTasks(runID)
+	var pending []Task
+	for _, task := range tasks {
+		st, ok := byID[task.ID]
+		switch {
+		case !ok:
+			// The run died before this task was stored
+			if err := o.createTask(task); err != nil {
+				return err
+			}
+			pending = append(pending, task)
+		case st.Status == string(StatusComplete):
+			if err := o.restoreOutputs(run, task, relocated); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", task.ID, err)
+			}
+			fmt.Printf("[SKIP] Already completed: %s\n", task.Type)
+		default:
+			if err := o.storage.UpdateTaskStatus(task.ID, string(StatusPending)); err != nil {
+				return fmt.Errorf("failed to reset task %s: %w", task.ID, err)
+			}
+			pending = append(pending, task)
+		}
+	}
+
+	if relocated {
+		if err := o.storage.UpdateRunWorkDir(runID, o.workDir); err != nil {
+			return err
+		}
+	}
+
+	o.updateRunStatus(StatusRunning)
+	return o.execute(ctx, p, tasks, withoutCompletedDeps(pending))
+}
+
+// restoreOutputs rewrites a completed task's files from their latest stored
+// content. With record set, the files are recorded again under the current
+// work directory so diagnostics there are attributed to the task
+func (o *Orchestrator) restoreOutputs(run *storage.Run, task Task, record bool) error {
+	files, err := o.storage.GetGeneratedFiles(task.ID)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no generated files recorded")
+	}
+
+	// Later rows (e.g. repairs) supersede earlier ones for the same path
+	// Paths are stored under the run's work directory; rows from before the
+	// run moved there were recorded again when it moved, so they are skipped
+	latest := make(map[string]storage.FileGenerated)
+	var order []string
+	for _, file := range files {
+		rel, err := filepath.Rel(run.WorkDir, file.FilePath)
+		if err != nil || validateRelativePath(rel) != nil {
+			continue
+		}
+		if _, ok := latest[rel]; !ok {
+			order = append(order, rel)
+		}
+		latest[rel] = file
+	}
+	if len(order) == 0 {
+		return fmt.Errorf("no generated files recorded under %s", run.WorkDir)
+	}
+
+	for _, rel := range order {
+		file := latest[rel]
+		outputPath := filepath.Join(o.workDir, rel)
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", outputPath, err)
+		}
+		if err := os.WriteFile(outputPath, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", outputPath, err)
+		}
+		if record {
+			if err := o.storage.SaveGeneratedFile(task.ID, outputPath, file.Content, file.Model); err != nil {
+				return fmt.Errorf("failed to save file record: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sameDir reports whether two paths name the same directory
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// withoutCompletedDeps drops dependencies on tasks that are not being run
+// Those tasks finished in an earlier attempt, so they are already satisfied
+func withoutCompletedDeps(pending []Task) []Task {
+	scheduled := make(map[string]bool, len(pending))
+	for _, task := range pending {
+		scheduled[task.ID] = true
+	}
+
+	result := make([]Task, 0, len(pending))
+	for _, task := range pending {
+		var deps []string
+		for _, dep := range task.DependsOn {
+			if scheduled[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		task.DependsOn = deps
+		result = append(result, task)
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/storage"
+)
+
+// TestResume verifies an interrupted run continues without redoing finished tasks
+func TestResume(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	promptsDir := t.TempDir()
+	os.WriteFile(filepath.Join(promptsDir, "models.txt"), []byte("models prompt"), 0644)
+	os.WriteFile(filepath.Join(promptsDir, "handlers.txt"), []byte("handlers prompt"), 0644)
+
+	p := &Pipeline{
+		Name:   "resume-test",
+		Module: "example.com/resume",
+		Tasks: []TaskSpec{
+			{ID: "models", Prompt: "models.txt", Output: "internal/models/todo.go"},
+			{ID: "handlers", Prompt: "handlers.txt", Output: "internal/handlers/todo.go", DependsOn: []string{"models"}},
+		},
+	}
+
+	// First run: the handlers task fails with a fatal error
+	var prompts []string
+	handlersFail := true
+	mockLLM := &mockLLMProvider{
+		completeFunc: func(ctx context.Context, prompt string) (string, error) {
+			prompts = append(prompts, prompt)
+			if strings.HasPrefix(prompt, "handlers") {
+				if handlersFail {
+					return "", errors.New("model crashed")
+				}
+				return "package handlers", nil
+			}
+			return "package models", nil
+		},
+	}
+
+	orch := New(mockLLM, db, workDir)
+	orch.promptsPath = promptsDir
+
+	if err := orch.Generate(context.Background(), p, "resume test"); err == nil {
+		t.Fatal("Expected first run to fail")
+	}
+	runID := orch.RunID()
+
+	run, err := orch.storage.GetRun(runID)
+	if err != nil {
+		t.Fatalf("Failed to load run: %v", err)
+	}
+	if run.Status != string(StatusFailed) {
+		t.Errorf("Expected run status failed, got %s", run.Status)
+	}
+
+	// Simulate losing the output directory along with the crashed process
+	os.RemoveAll(filepath.Join(workDir, "internal"))
+
+	// Second run: resume with a working model
+	handlersFail = false
+	prompts = nil
+	resumed := New(mockLLM, db, workDir)
+	resumed.promptsPath = promptsDir
+
+	if err := resumed.Resume(context.Background(), runID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	// Only the failed task is sent to the LLM again
+	if len(prompts) != 1 || prompts[0] != "handlers prompt" {
+		t.Errorf("Expected only the handlers prompt on resume, got %q", prompts)
+	}
+
+	// The completed task's output is restored from the database
+	data, err := os.ReadFile(filepath.Join(workDir, "internal", "models", "todo.go"))
+	if err != nil || string(data) != "package models" {
+		t.Errorf("Models output not restored: %q, %v", data, err)
+	}
+
+	tasks, err := resumed.storage.GetRunTasks(runID)
+	if err != nil {
+		t.Fatalf("Failed to load run tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Status != string(StatusComplete) {
+			t.Errorf("Task %s has status %s after resume", task.ID, task.Status)
+		}
+	}
+
+	run, _ = resumed.storage.GetRun(runID)
+	if run.Status != string(StatusComplete) {
+		t.Errorf("Expected run status complete, got %s", run.Status)
+	}
+}
+
+// TestResumeRelocated verifies a run resumed in another directory records its
+// restored files there, so later diagnostics and resumes find them
+func TestResumeRelocated(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	promptsDir := t.TempDir()
+	os.WriteFile(filepath.Join(promptsDir, "models.txt"), []byte("models prompt"), 0644)
+	os.WriteFile(filepath.Join(promptsDir, "handlers.txt"), []byte("handlers prompt"), 0644)
+
+	p := &Pipeline{
+		Name: "relocate-test",
+		Tasks: []TaskSpec{
+			{ID: "models", Prompt: "models.txt", Output: "models/todo.go"},
+			{ID: "handlers", Prompt: "handlers.txt", Output: "handlers/todo.go", DependsOn: []string{"models"}},
+		},
+	}
+
+	handlersFail := true
+	mockLLM := &mockLLMProvider{
+		completeFunc: func(ctx context.Context, prompt string) (string, error) {
+			if strings.HasPrefix(prompt, "handlers") {
+				if handlersFail {
+					return "", errors.New("model crashed")
+				}
+				return "package handlers", nil
+			}
+			return "package models", nil
+		},
+	}
+
+	orch := New(mockLLM, db, t.TempDir())
+	orch.promptsPath = promptsDir
+	if err := orch.Generate(context.Background(), p, "relocate test"); err == nil {
+		t.Fatal("Expected first run to fail")
+	}
+	runID := orch.RunID()
+
+	handlersFail = false
+	newDir := t.TempDir()
+	resumed := New(mockLLM, db, newDir)
+	resumed.promptsPath = promptsDir
+	if err := resumed.Resume(context.Background(), runID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	run, _ := resumed.storage.GetRun(runID)
+	if run.WorkDir != newDir {
+		t.Errorf("Expected the run to move to %s, got %s", newDir, run.WorkDir)
+	}
+	owners, err := resumed.storage.GetFileTasks()
+	if err != nil {
+		t.Fatalf("GetFileTasks failed: %v", err)
+	}
+	if owners[filepath.Join(newDir, "models", "todo.go")] != runID+"_models" {
+		t.Errorf("Expected the restored file attributed under the new directory, got %v", owners)
+	}
+
+	// The moved run resumes from its new directory using the rows recorded there
+	os.RemoveAll(filepath.Join(newDir, "models"))
+	again := New(mockLLM, db, newDir)
+	again.promptsPath = promptsDir
+	if err := again.Resume(context.Background(), runID); err != nil {
+		t.Fatalf("Second resume failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(newDir, "models", "todo.go")); err != nil || string(data) != "package models" {
+		t.Errorf("Models output not restored: %q, %v", data, err)
+	}
+}
+
+// TestResumeUnknownRun verifies resuming a missing run fails cleanly
+func TestResumeUnknownRun(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	orch := New(&mockLLMProvider{}, db, t.TempDir())
+	err := orch.Resume(context.Background(), "run_0")
+	if err == nil || !strings.Contains(err.Error(), "run not found") {
+		t.Errorf("Expected run not found error, got %v", err)
+	}
+}
+
+// TestWithoutCompletedDeps verifies dependencies on finished tasks are dropped
+func TestWithoutCompletedDeps(t *testing.T) {
+	pending := []Task{
+		{ID: "handlers", DependsOn: []string{"models"}},
+		{ID: "tests", DependsOn: []string{"models", "handlers"}},
+	}
+
+	result := withoutCompletedDeps(pending)
+
+	if len(result[0].DependsOn) != 0 {
+		t.Errorf("Expected no dependencies for handlers, got %v", result[0].DependsOn)
+	}
+	if len(result[1].DependsOn) != 1 || result[1].DependsOn[0] != "handlers" {
+		t.Errorf("Expected tests to depend only on handlers, got %v", result[1].DependsOn)
+	}
+
+	// Input tasks are left untouched
+	if len(pending[0].DependsOn) != 1 {
+		t.Error("withoutCompletedDeps modified its input")
+	}
+}
+
+// TestGetRunTasks verifies tasks are scoped to their run
+func TestGetRunTasks(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	store := storage.NewStorage(db)
+	for _, id := range []string{"run_1_models", "run_1_handlers", "run_10_models", "run_2_models"} {
+		store.CreateTask(storage.Task{ID: id, Type: "test", Status: string(StatusPending)})
+	}
+
+	tasks, err := store.GetRunTasks("run_1")
+	if err != nil {
+		t.Fatalf("Failed to load run tasks: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("Expected 2 tasks for run_1, got %d", len(tasks))
+	}
+}
@@ -0,0 +1,138 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+)
+
+// Attempt phases recorded in the task_attempts table
+const (
+	phaseGenerate = "generate"
+	phaseRepair   = "repair"
+	phaseEscalate = "escalate" // Regeneration with the next model in the chain
+)
+
+// BackoffPolicy controls the delay between retries of a failed LLM call
+// The delay doubles after every attempt up to Max, with random jitter
+type BackoffPolicy struct {
+	Base time.Duration // Delay before the first retry
+	Max  time.Duration // Upper bound for any single delay
+}
+
+// defaultBackoff waits 2s, 4s, 8s... capped at one minute
+var defaultBackoff = BackoffPolicy{
+	Base: 2 * time.Second,
+	Max:  time.Minute,
+}
+
+// SetBackoff overrides the retry backoff policy
+func (o *Orchestrator) SetBackoff(b BackoffPolicy) {
+	o.backoff = b
+}
+
+// delay returns the wait before the given retry (1-based)
+// Uses "equal jitter": half the exponential delay plus a random share of the rest
+func (b BackoffPolicy) delay(retry int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+
+	d := b.Base
+	for i := 1; i < retry && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// isRetryable reports whether a failed LLM call may succeed if repeated
+// Server errors, rate limiting, timeouts, dropped connections and truncated
+// responses are retryable; everything else (bad prompts, size limits) is fatal
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, llm.ErrIncomplete) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var temp interface{ Temporary() bool }
+	if errors.As(err, &temp) && temp.Temporary() {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return false
+}
+
+// generateWithRetry calls generateCode, retrying retryable failures with backoff
+// Every attempt is recorded in storage under the given phase
+func (o *Orchestrator) generateWithRetry(ctx context.Context, task Task, phase string, messages []llm.Message) (string, error) {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		code, err := o.generateCode(ctx, task, messages)
+		o.recordAttempt(task, phase, attempt, time.Since(start), err)
+
+		if err == nil {
+			return code, nil
+		}
+
+		// The run-wide deadline is not a per-call timeout, so never retry past it
+		if ctx.Err() != nil || !isRetryable(err) || attempt > o.limits.MaxRetries {
+			if attempt > 1 {
+				return "", fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+			}
+			return "", err
+		}
+
+		wait := o.backoff.delay(attempt)
+		fmt.Printf("    Attempt %d/%d for %s failed: %v (retrying in %v)\n",
+			attempt, o.limits.MaxRetries+1, task.Type, err, wait.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("cancelled while waiting to retry: %w", err)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// recordAttempt stores the outcome of an LLM call; failures to record are only logged
+func (o *Orchestrator) recordAttempt(task Task, phase string, attempt int, duration time.Duration, err error) {
+	record := storage.Attempt{
+		TaskID:   task.ID,
+		Phase:    phase,
+		Attempt:  attempt,
+		Status:   "success",
+		Model:    task.Model,
+		Duration: duration,
+	}
+	if err != nil {
+		record.Status = "failed"
+		record.Error = err.Error()
+	}
+	if options, jsonErr := json.Marshal(task.Options); jsonErr == nil {
+		record.Options = string(options)
+	}
+
+	if recErr := o.storage.RecordAttempt(record); recErr != nil {
+		fmt.Printf("Failed to record attempt for task %s: %v\n", task.ID, recErr)
+	}
+}
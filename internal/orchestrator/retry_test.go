@@ -0,0 +1,202 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+)
+
+// TestIsRetryable verifies retryable and fatal errors are told apart
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &llm.StatusError{Provider: "Ollama", StatusCode: 503}, true},
+		{"rate limited", &llm.StatusError{Provider: "Ollama", StatusCode: 429}, true},
+		{"bad request", &llm.StatusError{Provider: "Ollama", StatusCode: 400}, false},
+		{"wrapped server error", fmt.Errorf("LLM generation failed: %w", &llm.StatusError{StatusCode: 500}), true},
+		{"incomplete generation", fmt.Errorf("LLM generation failed: %w", llm.ErrIncomplete), true},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"request timeout", context.DeadlineExceeded, true},
+		{"cancelled", context.Canceled, false},
+		{"size limit", errors.New("output exceeds size limit: 100 > 50"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBackoffDelay verifies exponential growth, the cap and jitter bounds
+func TestBackoffDelay(t *testing.T) {
+	b := BackoffPolicy{Base: 100 * time.Millisecond, Max: 300 * time.Millisecond}
+
+	for retry, full := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 300 * time.Millisecond, // capped
+		9: 300 * time.Millisecond,
+	} {
+		for i := 0; i < 20; i++ {
+			d := b.delay(retry)
+			if d < full/2 || d > full {
+				t.Errorf("Retry %d: delay %v outside [%v, %v]", retry, d, full/2, full)
+			}
+		}
+	}
+
+	if d := (BackoffPolicy{}).delay(3); d != 0 {
+		t.Errorf("Expected zero delay without a base, got %v", d)
+	}
+}
+
+// TestExecuteTaskRetries verifies transient failures are retried and recorded
+func TestExecuteTaskRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		failWith    error
+		wantError   bool
+		wantCalls   int
+		wantAttempt []string
+	}{
+		{
+			name:        "recovers from server errors",
+			failures:    2,
+			failWith:    &llm.StatusError{Provider: "Ollama", StatusCode: 503},
+			wantCalls:   3,
+			wantAttempt: []string{"failed", "failed", "success"},
+		},
+		{
+			name:        "gives up after max retries",
+			failures:    10,
+			failWith:    &llm.StatusError{Provider: "Ollama", StatusCode: 500},
+			wantError:   true,
+			wantCalls:   4,
+			wantAttempt: []string{"failed", "failed", "failed", "failed"},
+		},
+		{
+			name:        "does not retry fatal errors",
+			failures:    10,
+			failWith:    &llm.StatusError{Provider: "Ollama", StatusCode: 404},
+			wantError:   true,
+			wantCalls:   1,
+			wantAttempt: []string{"failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := createTestDB(t)
+			defer cleanup()
+
+			workDir := t.TempDir()
+			os.WriteFile(filepath.Join(workDir, "prompt.txt"), []byte("prompt"), 0644)
+
+			calls := 0
+			mockLLM := &mockLLMProvider{
+				completeFunc: func(ctx context.Context, prompt string) (string, error) {
+					calls++
+					if calls <= tt.failures {
+						return "", tt.failWith
+					}
+					return "package main", nil
+				},
+			}
+
+			orch := &Orchestrator{
+				llm:         mockLLM,
+				storage:     storage.NewStorage(db),
+				workDir:     workDir,
+				promptsPath: workDir,
+				backoff:     BackoffPolicy{Base: time.Millisecond, Max: 2 * time.Millisecond},
+				limits: SafetyLimits{
+					MaxRetries:    3,
+					MaxOutputSize: 1024,
+				},
+			}
+
+			task := Task{ID: "run_1_main", Type: "main", PromptFile: "prompt.txt", OutputPath: "main.go"}
+			orch.storage.CreateTask(storage.Task{ID: task.ID, Type: string(task.Type), Status: string(StatusPending)})
+
+			err := orch.executeTask(context.Background(), task)
+			if tt.wantError != (err != nil) {
+				t.Fatalf("wantError %v, got %v", tt.wantError, err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("Expected %d LLM calls, got %d", tt.wantCalls, calls)
+			}
+
+			attempts, err := orch.storage.GetAttempts(task.ID)
+			if err != nil {
+				t.Fatalf("Failed to load attempts: %v", err)
+			}
+
+			var statuses []string
+			for i, a := range attempts {
+				statuses = append(statuses, a.Status)
+				if a.Attempt != i+1 || a.Phase != phaseGenerate {
+					t.Errorf("Unexpected attempt record: %+v", a)
+				}
+			}
+			if strings.Join(statuses, ",") != strings.Join(tt.wantAttempt, ",") {
+				t.Errorf("Expected attempts %v, got %v", tt.wantAttempt, statuses)
+			}
+		})
+	}
+}
+
+// TestAttemptOptionsRecorded verifies each call's options reach the provider and storage
+func TestAttemptOptionsRecorded(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "main.txt"), []byte("main prompt"), 0644)
+
+	mockLLM := &mockLLMProvider{}
+	orch := New(mockLLM, db, workDir)
+	orch.promptsPath = workDir
+	orch.SetOptions(llm.Options{NumCtx: 16384, Seed: llm.Int(3)})
+
+	p := &Pipeline{
+		Name:   "cli",
+		Module: "example.com/cli",
+		Tasks: []TaskSpec{{
+			ID: "main", Prompt: "main.txt", Output: "main.go",
+			Options: &llm.Options{Temperature: llm.Float(0.6)},
+		}},
+	}
+	if err := orch.Generate(context.Background(), p, "A CLI tool"); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if *mockLLM.lastOptions.Temperature != 0.6 || mockLLM.lastOptions.NumCtx != 16384 || *mockLLM.lastOptions.Seed != 3 {
+		t.Errorf("Unexpected options sent to provider: %+v", mockLLM.lastOptions)
+	}
+
+	attempts, err := orch.storage.GetAttempts(orch.RunID() + "_main")
+	if err != nil || len(attempts) != 1 {
+		t.Fatalf("Expected 1 recorded attempt, got %d (%v)", len(attempts), err)
+	}
+	want := `{"temperature":0.6,"top_p":0.9,"num_predict":4096,"num_ctx":16384,"seed":3}`
+	if attempts[0].Options != want {
+		t.Errorf("Expected recorded options %s, got %s", want, attempts[0].Options)
+	}
+}
@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// taskResult reports the outcome of a task run by a scheduler worker
+type taskResult struct {
+	task Task
+	err  error
+}
+
+// topoOrder sorts node IDs so every node comes after its dependencies
+// Nodes without ordering constraints keep their declaration order
+func topoOrder(ids []string, deps map[string][]string) ([]string, error) {
+	position := make(map[string]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+
+	remaining := make(map[string]int, len(ids))
+	dependents := make(map[string][]string, len(ids))
+	for _, id := range ids {
+		for _, dep := range deps[id] {
+			if _, ok := position[dep]; !ok {
+				return nil, fmt.Errorf("task %s depends on unknown task %s", id, dep)
+			}
+			remaining[id]++
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	var ready []string
+	for _, id := range ids {
+		if remaining[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	order := make([]string, 0, len(ids))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+
+		for _, next := range dependents[id] {
+			remaining[next]--
+			if remaining[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+		sort.Slice(ready, func(i, j int) bool { return position[ready[i]] < position[ready[j]] })
+	}
+
+	if len(order) != len(ids) {
+		var cyclic []string
+		for _, id := range ids {
+			if remaining[id] > 0 {
+				cyclic = append(cyclic, id)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle between tasks: %v", cyclic)
+	}
+
+	return order, nil
+}
+
+// runDAG executes tasks respecting their dependencies
+// Independent tasks run concurrently on up to workers goroutines. After the
+// first failure no new tasks are started, in-flight tasks are cancelled and
+// the error is returned once they finish
+func runDAG(ctx context.Context, tasks []Task, workers int, run func(context.Context, Task) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ids := make([]string, len(tasks))
+	deps := make(map[string][]string, len(tasks))
+	byID := make(map[string]Task, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		deps[task.ID] = task.DependsOn
+		byID[task.ID] = task
+	}
+
+	// Validate the graph up front so cycles fail before any LLM call is made
+	if _, err := topoOrder(ids, deps); err != nil {
+		return err
+	}
+
+	remaining := make(map[string]int, len(tasks))
+	dependents := make(map[string][]string, len(tasks))
+	var ready []Task
+	for _, task := range tasks {
+		remaining[task.ID] = len(task.DependsOn)
+		for _, dep := range task.DependsOn {
+			dependents[dep] = append(dependents[dep], task.ID)
+		}
+		if len(task.DependsOn) == 0 {
+			ready = append(ready, task)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start the worker pool
+	jobs := make(chan Task)
+	results := make(chan taskResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range jobs {
+				results <- taskResult{task: task, err: run(ctx, task)}
+			}
+		}()
+	}
+
+	inFlight := 0
+	var firstErr error
+	for {
+		// Hand ready tasks to idle workers; a worker is idle whenever inFlight < workers
+		for firstErr == nil && len(ready) > 0 && inFlight < workers {
+			jobs <- ready[0]
+			ready = ready[1:]
+			inFlight++
+		}
+
+		if inFlight == 0 {
+			break
+		}
+
+		res := <-results
+		inFlight--
+
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+
+		// Release dependents whose prerequisites are now all complete
+		for _, id := range dependents[res.task.ID] {
+			remaining[id]--
+			if remaining[id] == 0 {
+				ready = append(ready, byID[id])
+			}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTopoOrder verifies dependency ordering and cycle detection
+func TestTopoOrder(t *testing.T) {
+	ids := []string{"tests", "handlers", "models", "repository"}
+	deps := map[string][]string{
+		"tests":      {"handlers"},
+		"handlers":   {"models", "repository"},
+		"repository": {"models"},
+	}
+
+	order, err := topoOrder(ids, deps)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{"models", "repository", "handlers", "tests"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected order %v, got %v", want, order)
+	}
+
+	// Cycles are reported instead of silently dropping tasks
+	_, err = topoOrder([]string{"a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}})
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("Expected dependency cycle error, got %v", err)
+	}
+}
+
+// TestRunDAGRespectsDependencies verifies tasks only start after their dependencies
+func TestRunDAGRespectsDependencies(t *testing.T) {
+	tasks := []Task{
+		{ID: "tests", DependsOn: []string{"handlers", "models"}},
+		{ID: "handlers", DependsOn: []string{"models"}},
+		{ID: "repository", DependsOn: []string{"models"}},
+		{ID: "models"},
+	}
+
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	run := func(ctx context.Context, task Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range task.DependsOn {
+			if !finished[dep] {
+				t.Errorf("Task %s started before dependency %s finished", task.ID, dep)
+			}
+		}
+		finished[task.ID] = true
+		return nil
+	}
+
+	if err := runDAG(context.Background(), tasks, 4, run); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(finished) != len(tasks) {
+		t.Errorf("Expected %d tasks to run, got %d", len(tasks), len(finished))
+	}
+}
+
+// TestRunDAGParallel verifies independent tasks run concurrently
+func TestRunDAGParallel(t *testing.T) {
+	tasks := []Task{{ID: "a"}, {ID: "b"}}
+
+	// Each task waits until both have started, which only works in parallel
+	var started sync.WaitGroup
+	started.Add(len(tasks))
+	run := func(ctx context.Context, task Task) error {
+		started.Done()
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-time.After(2 * time.Second):
+			return errors.New("tasks did not run concurrently")
+		}
+	}
+
+	if err := runDAG(context.Background(), tasks, 2, run); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// TestRunDAGStopsOnFailure verifies dependents are skipped after a failure
+func TestRunDAGStopsOnFailure(t *testing.T) {
+	tasks := []Task{
+		{ID: "models"},
+		{ID: "handlers", DependsOn: []string{"models"}},
+	}
+
+	var mu sync.Mutex
+	var ran []string
+	run := func(ctx context.Context, task Task) error {
+		mu.Lock()
+		ran = append(ran, task.ID)
+		mu.Unlock()
+		if task.ID == "models" {
+			return errors.New("model generation failed")
+		}
+		return nil
+	}
+
+	err := runDAG(context.Background(), tasks, 2, run)
+	if err == nil || !strings.Contains(err.Error(), "model generation failed") {
+		t.Fatalf("Expected model generation error, got %v", err)
+	}
+
+	if len(ran) != 1 {
+		t.Errorf("Expected only the failing task to run, got %v", ran)
+	}
+}
@@ -0,0 +1,121 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// StreamingLLMProvider is implemented by providers that can deliver output
+// while it is generated. The orchestrator prefers streaming when available
+type StreamingLLMProvider interface {
+	CompleteStream(ctx context.Context, prompt string, opts llm.Options, fn llm.StreamFunc) (string, error)
+}
+
+// ChatLLMProvider is implemented by providers that accept role-separated
+// conversations. It is preferred over the flat prompt methods when available
+type ChatLLMProvider interface {
+	ChatStream(ctx context.Context, messages []llm.Message, opts llm.Options, fn llm.StreamFunc) (string, error)
+}
+
+// Progress describes a streaming LLM call in flight
+type Progress struct {
+	Task    Task
+	Text    string        // Text received in this update
+	Tokens  int           // Tokens received so far
+	Bytes   int           // Bytes received so far
+	Elapsed time.Duration // Time since the call started
+	Done    bool          // Set on the last update of a successful call
+}
+
+// TokensPerSecond returns the generation rate so far
+func (p Progress) TokensPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Tokens) / p.Elapsed.Seconds()
+}
+
+// ProgressFunc receives streaming progress updates
+// It is called from worker goroutines and must be safe for concurrent use
+type ProgressFunc func(Progress)
+
+// SetProgress sets a callback for live progress of streaming LLM calls
+func (o *Orchestrator) SetProgress(fn ProgressFunc) {
+	o.progress = fn
+}
+
+// complete sends a conversation to the LLM with the task's options. Chat providers receive the roles
+// as-is; others get the messages flattened into one prompt. Responses are
+// streamed when possible so oversized output is abandoned as soon as it
+// passes the limit
+func (o *Orchestrator) complete(ctx context.Context, task Task, messages []llm.Message) (string, error) {
+	if o.cache != nil {
+		return o.completeCached(ctx, task, messages, o.streamFunc(task))
+	}
+	return o.callLLM(ctx, task, messages, o.streamFunc(task))
+}
+
+// callLLM dispatches a conversation to the most capable API of the provider
+// serving the task's current model
+func (o *Orchestrator) callLLM(ctx context.Context, task Task, messages []llm.Message, fn llm.StreamFunc) (string, error) {
+	llmProvider, err := o.providerFor(task.Model)
+	if err != nil {
+		return "", err
+	}
+
+	switch provider := llmProvider.(type) {
+	case ChatLLMProvider:
+		return provider.ChatStream(ctx, messages, task.Options, fn)
+	case StreamingLLMProvider:
+		return provider.CompleteStream(ctx, flattenMessages(messages), task.Options, fn)
+	default:
+		return llmProvider.Complete(ctx, flattenMessages(messages), task.Options)
+	}
+}
+
+// streamFunc returns a callback enforcing the size limit and reporting progress
+func (o *Orchestrator) streamFunc(task Task) llm.StreamFunc {
+	start := time.Now()
+	var tokens, size int
+	return func(chunk llm.Chunk) error {
+		size += len(chunk.Text)
+		if chunk.Text != "" {
+			tokens++
+		}
+		if size > o.limits.MaxOutputSize {
+			return fmt.Errorf("output exceeds size limit while streaming: > %d", o.limits.MaxOutputSize)
+		}
+
+		if o.progress != nil {
+			update := Progress{
+				Task:    task,
+				Text:    chunk.Text,
+				Tokens:  tokens,
+				Bytes:   size,
+				Elapsed: time.Since(start),
+				Done:    chunk.Done,
+			}
+			// Prefer the provider's own statistics for the final rate
+			if chunk.Done && chunk.EvalCount > 0 && chunk.EvalDuration > 0 {
+				update.Tokens = chunk.EvalCount
+				update.Elapsed = chunk.EvalDuration
+			}
+			o.progress(update)
+		}
+		return nil
+	}
+}
+
+// flattenMessages joins a conversation into a single prompt for providers
+// without a chat API. A lone user message is passed through unchanged
+func flattenMessages(messages []llm.Message) string {
+	parts := make([]string, len(messages))
+	for i, msg := range messages {
+		parts[i] = msg.Content
+	}
+	return strings.Join(parts, "\n\n")
+}
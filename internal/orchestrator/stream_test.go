@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorchestrator-poc/internal/llm"
+	"gorchestrator-poc/internal/storage"
+)
+
+// mockStreamingProvider streams a fixed list of chunks
+type mockStreamingProvider struct {
+	mockLLMProvider
+	chunks    []llm.Chunk
+	delivered int
+}
+
+func (m *mockStreamingProvider) CompleteStream(ctx context.Context, prompt string, opts llm.Options, fn llm.StreamFunc) (string, error) {
+	var full strings.Builder
+	for _, chunk := range m.chunks {
+		m.delivered++
+		full.WriteString(chunk.Text)
+		if err := fn(chunk); err != nil {
+			return "", err
+		}
+	}
+	return full.String(), nil
+}
+
+// TestStreamingProgress verifies streamed chunks are reported as progress
+func TestStreamingProgress(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	provider := &mockStreamingProvider{chunks: []llm.Chunk{
+		{Text: "package "},
+		{Text: "models"},
+		{Text: "\n", Done: true, EvalCount: 3, EvalDuration: 2 * time.Second},
+	}}
+
+	var updates []Progress
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+	}
+	orch.SetProgress(func(p Progress) {
+		updates = append(updates, p)
+	})
+
+	task := Task{ID: "run_1_models", Type: "generate_models"}
+	code, err := orch.generateCode(context.Background(), task, []llm.Message{{Role: llm.RoleUser, Content: "prompt"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if code != "package models" {
+		t.Errorf("Expected streamed code, got %q", code)
+	}
+
+	if len(updates) != 3 {
+		t.Fatalf("Expected 3 progress updates, got %d", len(updates))
+	}
+	if updates[1].Text != "models" || updates[1].Tokens != 2 || updates[1].Bytes != 14 {
+		t.Errorf("Unexpected intermediate update: %+v", updates[1])
+	}
+	if updates[1].Task.ID != task.ID {
+		t.Errorf("Expected update for %s, got %s", task.ID, updates[1].Task.ID)
+	}
+
+	// The final update uses the provider's statistics
+	last := updates[2]
+	if !last.Done || last.Tokens != 3 || last.TokensPerSecond() != 1.5 {
+		t.Errorf("Unexpected final update: %+v (%.1f tok/s)", last, last.TokensPerSecond())
+	}
+}
+
+// TestStreamingSizeLimit verifies oversized output is abandoned mid-stream
+func TestStreamingSizeLimit(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	provider := &mockStreamingProvider{chunks: []llm.Chunk{
+		{Text: strings.Repeat("x", 60)},
+		{Text: strings.Repeat("x", 60)},
+		{Text: strings.Repeat("x", 60)},
+		{Done: true},
+	}}
+
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		limits:  SafetyLimits{MaxOutputSize: 100},
+	}
+
+	_, err := orch.generateCode(context.Background(), Task{ID: "run_1_models"}, []llm.Message{{Role: llm.RoleUser, Content: "prompt"}})
+	if err == nil {
+		t.Fatal("Expected size limit error, got nil")
+	}
+	if !strings.Contains(err.Error(), "exceeds size limit while streaming") {
+		t.Errorf("Expected streaming size limit error, got %v", err)
+	}
+	if provider.delivered != 2 {
+		t.Errorf("Expected stream to stop after 2 chunks, got %d", provider.delivered)
+	}
+	if isRetryable(err) {
+		t.Error("Size limit errors should not be retried")
+	}
+}
+
+// mockChatProvider records the conversations it receives
+type mockChatProvider struct {
+	mockLLMProvider
+	conversations [][]llm.Message
+	reply         func(call int) string
+}
+
+func (m *mockChatProvider) ChatStream(ctx context.Context, messages []llm.Message, opts llm.Options, fn llm.StreamFunc) (string, error) {
+	m.conversations = append(m.conversations, append([]llm.Message(nil), messages...))
+	reply := m.reply(len(m.conversations))
+	if err := fn(llm.Chunk{Text: reply, Done: true}); err != nil {
+		return "", err
+	}
+	return reply, nil
+}
+
+// TestChatProviderReceivesRoles verifies chat providers get separate system and user messages
+func TestChatProviderReceivesRoles(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	provider := &mockChatProvider{reply: func(int) string { return "package models" }}
+	orch := &Orchestrator{
+		llm:     provider,
+		storage: storage.NewStorage(db),
+		limits:  SafetyLimits{MaxOutputSize: 1024},
+	}
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You write Go."},
+		{Role: llm.RoleUser, Content: "Generate models."},
+	}
+	if _, err := orch.generateCode(context.Background(), Task{ID: "run_1_models"}, messages); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(provider.conversations) != 1 || len(provider.conversations[0]) != 2 {
+		t.Fatalf("Expected one conversation of 2 messages, got %+v", provider.conversations)
+	}
+	if provider.conversations[0][0].Role != llm.RoleSystem {
+		t.Errorf("Expected system message first, got %+v", provider.conversations[0][0])
+	}
+	if provider.callCount != 0 {
+		t.Error("Complete should not be used when chat is available")
+	}
+}
+
+// TestFlattenMessages verifies conversations are joined for prompt-only providers
+func TestFlattenMessages(t *testing.T) {
+	single := []llm.Message{{Role: llm.RoleUser, Content: "Generate models."}}
+	if got := flattenMessages(single); got != "Generate models." {
+		t.Errorf("Expected a lone user message unchanged, got %q", got)
+	}
+
+	conversation := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You write Go."},
+		{Role: llm.RoleUser, Content: "Generate models."},
+	}
+	if got := flattenMessages(conversation); got != "You write Go.\n\nGenerate models." {
+		t.Errorf("Unexpected flattened prompt %q", got)
+	}
+}
@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gorchestrator-poc/internal/llm"
+)
+
+// PromptData is the data available to prompt templates
+// Prompt files are Go text/template templates, e.g. "module {{.Module}}"
+type PromptData struct {
+	Prompt   string               // Project description given with -prompt
+	Module   string               // Module path of the generated project
+	Entities []string             // Entity names declared by the pipeline
+	Entity   string               // First entity, for single-entity pipelines
+	Task     TaskInfo             // The task the prompt is rendered for
+	Deps     map[string]DepOutput // Outputs of dependencies, by pipeline task ID
+	Vars     map[string]string    // Pipeline variables, overridden by -var flags
+}
+
+// TaskInfo describes the task a prompt is rendered for
+type TaskInfo struct {
+	ID         string // Pipeline task ID
+	Type       string
+	Input      string
+	Output     string // Output file, relative to the work directory
+	ImportPath string // Import path of the output file's package
+}
+
+// DepOutput describes the generated output of a dependency
+type DepOutput struct {
+	Output     string // Output file, relative to the work directory
+	ImportPath string // Import path of the output file's package
+	Code       string // Complete generated source
+	API        string // Exported declarations and signatures only
+}
+
+// systemTemplate names the optional template block holding the system message
+const systemTemplate = "system"
+
+// promptFuncs are helpers available inside prompt templates
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// SetVars sets template variables, overriding any declared by the pipeline
+func (o *Orchestrator) SetVars(vars map[string]string) {
+	o.cliVars = vars
+}
+
+// renderPrompt executes a prompt file as a template for the given task
+// A {{define "system"}} block, if present, becomes the system message and the
+// rest of the file the user message. Unknown variables are errors so typos
+// surface before any LLM call
+func (o *Orchestrator) renderPrompt(task Task, name, content string) (system, user string, err error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+	}
+
+	data, err := o.promptData(task)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render prompt template %s: %w", name, err)
+	}
+	user = strings.TrimSpace(buf.String())
+
+	if sys := tmpl.Lookup(systemTemplate); sys != nil {
+		buf.Reset()
+		if err := sys.Execute(&buf, data); err != nil {
+			return "", "", fmt.Errorf("failed to render system section of %s: %w", name, err)
+		}
+		system = strings.TrimSpace(buf.String())
+	}
+
+	return system, user, nil
+}
+
+// promptData collects the template data for a task
+func (o *Orchestrator) promptData(task Task) (PromptData, error) {
+	data := PromptData{
+		Prompt:   o.project,
+		Module:   o.modulePath(),
+		Entities: o.entities,
+		Task: TaskInfo{
+			ID:         task.Name,
+			Type:       string(task.Type),
+			Input:      task.Input,
+			Output:     task.OutputPath,
+			ImportPath: o.importPath(task.OutputPath),
+		},
+		Deps: make(map[string]DepOutput, len(task.DependsOn)),
+		Vars: o.vars,
+	}
+	if len(o.entities) > 0 {
+		data.Entity = o.entities[0]
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+
+	for _, depID := range task.DependsOn {
+		dep, ok := o.tasks[depID]
+		if !ok {
+			continue
+		}
+
+		stored, err := o.storage.GetTask(depID)
+		if err != nil {
+			return data, fmt.Errorf("failed to load output of %s: %w", depID, err)
+		}
+
+		data.Deps[dep.Name] = DepOutput{
+			Output:     dep.OutputPath,
+			ImportPath: o.importPath(dep.OutputPath),
+			Code:       stored.Output,
+			API:        extractFilesAPI(stored.Output, dep.OutputPath),
+		}
+	}
+
+	return data, nil
+}
+
+// withVars returns a copy of the pipeline with extra variables merged in
+// The merged pipeline is what gets stored, so resumed runs render the same prompts
+func (p *Pipeline) withVars(vars map[string]string) *Pipeline {
+	if len(vars) == 0 {
+		return p
+	}
+
+	merged := make(map[string]string, len(p.Vars)+len(vars))
+	for k, v := range p.Vars {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+
+	copied := *p
+	copied.Vars = merged
+	return &copied
+}
+
+// withOptions returns a copy of the pipeline whose options are layered over base
+// Like withVars, this makes the stored pipeline reproduce the run on resume
+func (p *Pipeline) withOptions(base llm.Options) *Pipeline {
+	if p.Options != nil {
+		base = base.Merge(*p.Options)
+	}
+
+	copied := *p
+	copied.Options = &base
+	return &copied
+}
@@ -0,0 +1,245 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/prompts"
+)
+
+// TestRenderPrompt verifies prompt files are rendered as templates
+func TestRenderPrompt(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	orch := &Orchestrator{
+		storage:  storage.NewStorage(db),
+		module:   "example.com/library",
+		project:  "REST API for a library",
+		entities: []string{"Book", "Author"},
+		vars:     map[string]string{"table": "books"},
+	}
+
+	models := Task{ID: "run_1_models", Name: "models", OutputPath: "internal/models/book.go"}
+	handlers := Task{
+		ID:         "run_1_handlers",
+		Name:       "handlers",
+		Type:       "generate_handlers",
+		Input:      "REST endpoints",
+		OutputPath: "internal/handlers/book.go",
+		DependsOn:  []string{models.ID},
+	}
+	orch.tasks = map[string]Task{models.ID: models, handlers.ID: handlers}
+	orch.storage.CreateTask(storage.Task{ID: models.ID, Type: "models", Status: string(StatusComplete)})
+	orch.storage.UpdateTaskOutput(models.ID, modelsSource)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "plain text passes through",
+			template: "Generate handlers.",
+			expected: "Generate handlers.",
+		},
+		{
+			name:     "project and module",
+			template: "{{.Prompt}} in module {{.Module}}",
+			expected: "REST API for a library in module example.com/library",
+		},
+		{
+			name:     "entities",
+			template: "{{.Entity}}: {{join .Entities \", \"}} ({{lower .Entity}})",
+			expected: "Book: Book, Author (book)",
+		},
+		{
+			name:     "task fields",
+			template: "{{.Task.ID}} {{.Task.Type}} {{.Task.Input}} {{.Task.ImportPath}}",
+			expected: "handlers generate_handlers REST endpoints example.com/library/internal/handlers",
+		},
+		{
+			name:     "variables",
+			template: "table {{.Vars.table}}",
+			expected: "table books",
+		},
+		{
+			name:     "dependency import path",
+			template: "import {{(index .Deps \"models\").ImportPath}}",
+			expected: "import example.com/library/internal/models",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := orch.renderPrompt(handlers, "test.txt", tt.template)
+			if err != nil {
+				t.Fatalf("Failed to render: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	// Dependency code is available in full and as an API surface
+	_, got, err := orch.renderPrompt(handlers, "test.txt", "{{with .Deps.models}}{{.Code}}\n---\n{{.API}}{{end}}")
+	if err != nil {
+		t.Fatalf("Failed to render dependency code: %v", err)
+	}
+	full, api, _ := strings.Cut(got, "\n---\n")
+	if !strings.Contains(full, "title is required") {
+		t.Error("Code should contain the complete dependency source")
+	}
+	if strings.Contains(api, "title is required") || !strings.Contains(api, "func (t *Todo) Validate() error") {
+		t.Errorf("API should contain signatures only:\n%s", api)
+	}
+}
+
+// TestRenderPromptErrors verifies broken templates and unknown variables fail early
+func TestRenderPromptErrors(t *testing.T) {
+	orch := &Orchestrator{}
+	task := Task{ID: "run_1_models", Name: "models"}
+
+	tests := []struct {
+		name     string
+		template string
+		errMsg   string
+	}{
+		{"syntax error", "{{.Prompt", "failed to parse prompt template"},
+		{"unknown variable", "{{.Vars.missing}}", "failed to render prompt template"},
+		{"unknown field", "{{.Nope}}", "failed to render prompt template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := orch.renderPrompt(task, "bad.txt", tt.template)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestRenderPromptSystemSection verifies a system block is split from the user prompt
+func TestRenderPromptSystemSection(t *testing.T) {
+	orch := &Orchestrator{module: "example.com/todo"}
+	task := Task{ID: "run_1_models", Name: "models"}
+
+	content := "{{define \"system\"}}\nYou write Go for {{.Module}}.\n{{end}}\nGenerate the models.\n"
+	system, user, err := orch.renderPrompt(task, "models.txt", content)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if system != "You write Go for example.com/todo." {
+		t.Errorf("Unexpected system section %q", system)
+	}
+	if user != "Generate the models." {
+		t.Errorf("Unexpected user prompt %q", user)
+	}
+
+	// Files without a system block only have a user prompt
+	system, user, _ = orch.renderPrompt(task, "plain.txt", "Generate the models.")
+	if system != "" || user != "Generate the models." {
+		t.Errorf("Expected user prompt only, got system %q and user %q", system, user)
+	}
+}
+
+// TestBundledPromptsRender verifies the embedded prompt files are valid templates
+func TestBundledPromptsRender(t *testing.T) {
+	orch := &Orchestrator{
+		promptsFS: prompts.FS,
+		project:   "REST API for todo list",
+	}
+
+	for _, task := range DefaultPipeline().newTasks("run_test") {
+		task.DependsOn = nil
+		system, prompt, err := orch.loadPrompt(task)
+		if err != nil {
+			t.Fatalf("Failed to render %s: %v", task.PromptFile, err)
+		}
+		if !strings.HasPrefix(system, "You are a Go code generator") {
+			t.Errorf("%s has no system section, got %q", task.PromptFile, system)
+		}
+		if strings.Contains(prompt, "You are a Go code generator") {
+			t.Errorf("%s repeats the system section in the user prompt", task.PromptFile)
+		}
+		if !strings.Contains(prompt, "Project: REST API for todo list") {
+			t.Errorf("%s does not include the project description", task.PromptFile)
+		}
+		if strings.Contains(prompt, "{{") {
+			t.Errorf("%s contains unrendered template actions", task.PromptFile)
+		}
+	}
+}
+
+// TestPipelineWithVars verifies command line variables override pipeline ones
+func TestPipelineWithVars(t *testing.T) {
+	p := &Pipeline{Vars: map[string]string{"entity": "Todo", "table": "todos"}}
+
+	if got := p.withVars(nil); got != p {
+		t.Error("Expected pipeline unchanged without extra variables")
+	}
+
+	merged := p.withVars(map[string]string{"entity": "Book"})
+	if merged.Vars["entity"] != "Book" || merged.Vars["table"] != "todos" {
+		t.Errorf("Unexpected merged variables: %v", merged.Vars)
+	}
+	if p.Vars["entity"] != "Todo" {
+		t.Error("Original pipeline variables should not be modified")
+	}
+}
+
+// TestGenerateStoresVars verifies variables are stored with the run for resume
+func TestGenerateStoresVars(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	promptsDir := t.TempDir()
+	os.WriteFile(filepath.Join(promptsDir, "main.txt"), []byte("{{.Prompt}} with {{.Vars.store}}"), 0644)
+
+	var captured string
+	mockLLM := &mockLLMProvider{
+		completeFunc: func(ctx context.Context, prompt string) (string, error) {
+			captured = prompt
+			return "package main\n\nfunc main() {}", nil
+		},
+	}
+
+	orch := New(mockLLM, db, t.TempDir())
+	orch.promptsPath = promptsDir
+	orch.SetVars(map[string]string{"store": "sqlite"})
+
+	p := &Pipeline{
+		Name:   "cli",
+		Module: "example.com/cli",
+		Vars:   map[string]string{"store": "memory"},
+		Tasks:  []TaskSpec{{ID: "main", Prompt: "main.txt", Output: "main.go"}},
+	}
+	if err := orch.Generate(context.Background(), p, "A CLI tool"); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if captured != "A CLI tool with sqlite" {
+		t.Errorf("Unexpected rendered prompt %q", captured)
+	}
+
+	run, err := orch.storage.GetRun(orch.RunID())
+	if err != nil {
+		t.Fatalf("Failed to load run: %v", err)
+	}
+	stored, err := ParsePipeline([]byte(run.Pipeline))
+	if err != nil {
+		t.Fatalf("Failed to parse stored pipeline: %v", err)
+	}
+	if stored.Vars["store"] != "sqlite" {
+		t.Errorf("Expected stored variable sqlite, got %q", stored.Vars["store"])
+	}
+}
@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+)
+
+// testFuncPattern matches the declaration of a top-level test function
+var testFuncPattern = regexp.MustCompile(`(?m)^func (Test\w*)\(`)
+
+// RecordTestResults stores the per-package and per-test outcomes of a
+// go test run for the current run. Each test is attributed to the task, and
+// model, whose generated _test.go file declares it
+func (o *Orchestrator) RecordTestResults(result validator.ValidationResult) error {
+	if result.Tests == nil {
+		return fmt.Errorf("no test results to record: %v", result.Error)
+	}
+	report := result.Tests
+	o.AttributeDiagnostics(result.Diagnostics)
+
+	owners := o.testOwners()
+	models := make(map[string]string)
+	tests := make([]storage.TestResult, 0, len(report.Tests))
+	for _, t := range report.Tests {
+		taskID := owners[o.packageDir(t.Package)+"."+topLevelTest(t.Test)]
+		if _, ok := models[taskID]; !ok && taskID != "" {
+			if task, err := o.storage.GetTask(taskID); err == nil {
+				models[taskID] = task.Model
+			}
+		}
+		tests = append(tests, storage.TestResult{
+			Package:  t.Package,
+			Test:     t.Test,
+			Status:   t.Status,
+			Output:   t.Output,
+			TaskID:   taskID,
+			Model:    models[taskID],
+			Duration: t.Elapsed,
+		})
+	}
+
+	packages := make([]storage.TestResult, 0, len(report.Packages))
+	for _, p := range report.Packages {
+		packages = append(packages, storage.TestResult{
+			Package:  p.Package,
+			Status:   p.Status,
+			Output:   p.Output,
+			Duration: p.Elapsed,
+		})
+	}
+
+	return o.storage.RecordTestResults(o.runID, packages, tests)
+}
+
+// testOwners maps "dir.TestName" to the task whose generated _test.go file
+// declares the test, with dir relative to the work directory
+func (o *Orchestrator) testOwners() map[string]string {
+	files, err := o.storage.GetFileTasks()
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return nil
+	}
+
+	owners := make(map[string]string)
+	for file, taskID := range files {
+		if !strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(o.workDir, filepath.Dir(file))
+		if err != nil {
+			continue
+		}
+		for _, match := range testFuncPattern.FindAllStringSubmatch(string(data), -1) {
+			owners[filepath.ToSlash(rel)+"."+match[1]] = taskID
+		}
+	}
+	return owners
+}
+
+// packageDir returns the directory of a generated package relative to the
+// work directory, "." for the module root
+func (o *Orchestrator) packageDir(importPath string) string {
+	module := o.modulePath()
+	if importPath == module {
+		return "."
+	}
+	return strings.TrimPrefix(importPath, module+"/")
+}
+
+// topLevelTest strips the subtest names from a test name
+func topLevelTest(name string) string {
+	top, _, _ := strings.Cut(name, "/")
+	return top
+}
@@ -0,0 +1,66 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"gorchestrator-poc/internal/storage"
+	"gorchestrator-poc/internal/validator"
+)
+
+// TestRecordTestResults verifies test outcomes are stored with the task and model that wrote them
+func TestRecordTestResults(t *testing.T) {
+	db, cleanup := createTestDB(t)
+	defer cleanup()
+
+	orch := &Orchestrator{storage: storage.NewStorage(db), workDir: t.TempDir(), runID: "run_1"}
+	orch.storage.CreateRun(storage.Run{ID: "run_1", Pipeline: "{}", WorkDir: orch.workDir, Status: string(StatusRunning)})
+
+	task := Task{ID: "run_1_tests", OutputPath: "internal/models/todo_test.go"}
+	orch.storage.CreateTask(storage.Task{ID: task.ID, Type: "test", Status: string(StatusComplete)})
+	orch.storage.UpdateTaskModel(task.ID, "codellama:7b")
+	orch.saveOutput(task, "package models\n\nimport \"testing\"\n\nfunc TestValidate(t *testing.T) {}\n")
+
+	result := validator.ValidationResult{
+		Tool: "go test",
+		Tests: &validator.TestReport{
+			Packages: []validator.TestResult{
+				{Package: "todo-api/internal/models", Status: validator.TestFail},
+			},
+			Tests: []validator.TestResult{
+				{Package: "todo-api/internal/models", Test: "TestValidate/empty_title", Status: validator.TestFail, Output: "title is required"},
+				{Package: "todo-api/internal/models", Test: "TestHelper", Status: validator.TestPass},
+			},
+		},
+	}
+
+	if err := orch.RecordTestResults(result); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results, err := orch.storage.GetTestResults("run_1")
+	if err != nil {
+		t.Fatalf("Failed to load test results: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 stored results, got %d", len(results))
+	}
+
+	failed := results[0]
+	if failed.Status != validator.TestFail || failed.Output != "title is required" {
+		t.Errorf("Unexpected failed result: %+v", failed)
+	}
+	if failed.TaskID != task.ID || failed.Model != "codellama:7b" {
+		t.Errorf("Expected the subtest attributed to %s [codellama:7b], got %s [%s]", task.ID, failed.TaskID, failed.Model)
+	}
+	if results[1].TaskID != "" {
+		t.Errorf("Tests no generated file declares should be unattributed, got %q", results[1].TaskID)
+	}
+
+	// Recording again replaces the run's results instead of adding to them
+	if err := orch.RecordTestResults(result); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if results, _ := orch.storage.GetTestResults("run_1"); len(results) != 2 {
+		t.Errorf("Expected 2 results after a second run, got %d", len(results))
+	}
+}
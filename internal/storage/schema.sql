@@ -1,6 +1,17 @@
 -- Schema for tracking code generation tasks and their outputs
 -- This schema is embedded in the binary and executed on initialization
 
+-- One row per generation run; the pipeline is kept so the run can be resumed
+CREATE TABLE IF NOT EXISTS runs (
+    id TEXT PRIMARY KEY,
+    pipeline TEXT NOT NULL,
+    project TEXT,
+    work_dir TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS tasks (
     id TEXT PRIMARY KEY,
     type TEXT NOT NULL,
@@ -8,6 +19,7 @@ CREATE TABLE IF NOT EXISTS tasks (
     output TEXT,
     status TEXT NOT NULL,
     error TEXT,
+    model TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -17,12 +29,73 @@ CREATE TABLE IF NOT EXISTS files_generated (
     task_id TEXT,
     file_path TEXT NOT NULL,
     content TEXT,
+    model TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks(id)
+);
+
+-- Every LLM call made for a task, including retries and repairs
+CREATE TABLE IF NOT EXISTS task_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT NOT NULL,
+    phase TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    options TEXT,
+    model TEXT,
+    duration_ms INTEGER,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (task_id) REFERENCES tasks(id)
 );
 
+-- LLM responses keyed by a hash of model, messages and options
+-- Shared by all runs; CleanAllTasks leaves it intact
+CREATE TABLE IF NOT EXISTS llm_cache (
+    key TEXT PRIMARY KEY,
+    model TEXT NOT NULL,
+    response TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Outcome of each package of the generated module in a go test run
+CREATE TABLE IF NOT EXISTS test_packages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT NOT NULL,
+    package TEXT NOT NULL,
+    status TEXT NOT NULL,
+    output TEXT,
+    duration_ms INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (run_id) REFERENCES runs(id)
+);
+
+-- Outcome of each generated test, with the task and model that wrote it
+CREATE TABLE IF NOT EXISTS test_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT NOT NULL,
+    package TEXT NOT NULL,
+    test TEXT NOT NULL,
+    status TEXT NOT NULL,
+    output TEXT,
+    task_id TEXT,
+    model TEXT,
+    duration_ms INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (run_id) REFERENCES runs(id)
+);
+
 -- Index for faster task lookups by status
 CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
 
 -- Index for faster file lookups by task
-CREATE INDEX IF NOT EXISTS idx_files_task_id ON files_generated(task_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_files_task_id ON files_generated(task_id);
+
+-- Index for faster attempt lookups by task
+CREATE INDEX IF NOT EXISTS idx_attempts_task_id ON task_attempts(task_id);
+
+-- Index for expiring old cache entries
+CREATE INDEX IF NOT EXISTS idx_llm_cache_created_at ON llm_cache(created_at);
+
+-- Index for comparing test outcomes across runs
+CREATE INDEX IF NOT EXISTS idx_test_results_test ON test_results(package, test);
\ No newline at end of file
@@ -15,6 +15,17 @@ import (
 //go:embed schema.sql
 var schemaSQL string
 
+// Run represents a generation run in the database
+type Run struct {
+	ID        string
+	Pipeline  string // JSON pipeline definition the run was started with
+	Project   string
+	WorkDir   string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 // Task represents a code generation task in the database
 type Task struct {
 	ID        string
@@ -23,6 +34,7 @@ type Task struct {
 	Output    string
 	Status    string
 	Error     string
+	Model     string // Model that produced the accepted output
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -33,6 +45,43 @@ type FileGenerated struct {
 	TaskID    string
 	FilePath  string
 	Content   string
+	Model     string // Model that generated this version of the file
+	CreatedAt time.Time
+}
+
+// Attempt represents a single LLM call made while executing a task
+type Attempt struct {
+	ID        int64
+	TaskID    string
+	Phase     string // What the call was for (e.g. "generate", "repair")
+	Attempt   int    // 1-based attempt number within the phase
+	Status    string // "success" or "failed"
+	Error     string
+	Options   string // JSON of the generation options sent with the call
+	Model     string // Model the call was sent to
+	Duration  time.Duration
+	CreatedAt time.Time
+}
+
+// TestResult is the outcome of a generated test, or of a package when Test is empty
+type TestResult struct {
+	ID        int64
+	RunID     string
+	Package   string
+	Test      string
+	Status    string // "pass", "fail" or "skip"
+	Output    string // Output of failures
+	TaskID    string // Task that generated the test; tests only
+	Model     string // Model that generated the test; tests only
+	Duration  time.Duration
+	CreatedAt time.Time
+}
+
+// CacheEntry is a cached LLM response
+type CacheEntry struct {
+	Key       string // Hash of the model, messages and options of the request
+	Model     string
+	Response  string
 	CreatedAt time.Time
 }
 
@@ -60,6 +109,11 @@ func InitDB(dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create schema: %w", err)
 	}
 
+	// Add columns that tables created by older versions are missing
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
 	// Enable foreign keys (disabled by default in SQLite)
 	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
@@ -73,11 +127,136 @@ func InitDB(dbPath string) (*sql.DB, error) {
 	return db, nil
 }
 
+// columnMigrations lists columns added to tables after they were first
+// released; CREATE TABLE IF NOT EXISTS leaves existing tables unchanged
+var columnMigrations = []struct {
+	table, column, definition string
+}{
+	{"tasks", "model", "TEXT"},
+	{"files_generated", "model", "TEXT"},
+	{"task_attempts", "options", "TEXT"},
+	{"task_attempts", "model", "TEXT"},
+}
+
+// migrate adds any missing columns from columnMigrations
+func migrate(db *sql.DB) error {
+	for _, m := range columnMigrations {
+		exists, err := hasColumn(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether a table has the named column
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name, kind string
+			notNull    bool
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultVal, &primaryKey); err != nil {
+			return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 // NewStorage creates a new storage instance with the given database
 func NewStorage(db *sql.DB) *Storage {
 	return &Storage{db: db}
 }
 
+// CreateRun inserts a new run into the database
+func (s *Storage) CreateRun(run Run) error {
+	query := `
+		INSERT INTO runs (id, pipeline, project, work_dir, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	_, err := s.db.Exec(query, run.ID, run.Pipeline, run.Project, run.WorkDir, run.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+	return nil
+}
+
+// UpdateRunStatus updates the status and timestamp of a run
+func (s *Storage) UpdateRunStatus(runID string, status string) error {
+	query := `
+		UPDATE runs
+		SET status = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, status, time.Now(), runID)
+	if err != nil {
+		return fmt.Errorf("failed to update run status: %w", err)
+	}
+	return nil
+}
+
+// UpdateRunWorkDir records that a run's output now lives in another directory
+func (s *Storage) UpdateRunWorkDir(runID string, workDir string) error {
+	query := `
+		UPDATE runs
+		SET work_dir = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, workDir, time.Now(), runID)
+	if err != nil {
+		return fmt.Errorf("failed to update run work directory: %w", err)
+	}
+	return nil
+}
+
+// GetRun retrieves a run by ID
+func (s *Storage) GetRun(runID string) (*Run, error) {
+	query := `
+		SELECT id, pipeline, project, work_dir, status, created_at, updated_at
+		FROM runs
+		WHERE id = ?
+	`
+	var run Run
+	var nullProject sql.NullString
+
+	err := s.db.QueryRow(query, runID).Scan(
+		&run.ID, &run.Pipeline, &nullProject, &run.WorkDir,
+		&run.Status, &run.CreatedAt, &run.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("run not found: %s", runID)
+		}
+		return nil, fmt.Errorf("failed to get run: %w", err)
+	}
+
+	if nullProject.Valid {
+		run.Project = nullProject.String
+	}
+
+	return &run, nil
+}
+
 // CreateTask inserts a new task into the database
 func (s *Storage) CreateTask(task Task) error {
 	query := `
@@ -134,32 +313,237 @@ func (s *Storage) UpdateTaskError(taskID string, errorMsg string) error {
 	return nil
 }
 
-// SaveGeneratedFile stores a generated file in the database
-func (s *Storage) SaveGeneratedFile(taskID, filePath, content string) error {
+// UpdateTaskModel records which model produced a task's output
+func (s *Storage) UpdateTaskModel(taskID string, model string) error {
 	query := `
-		INSERT INTO files_generated (task_id, file_path, content, created_at)
-		VALUES (?, ?, ?, ?)
+		UPDATE tasks
+		SET model = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, model, time.Now(), taskID)
+	if err != nil {
+		return fmt.Errorf("failed to update task model: %w", err)
+	}
+	return nil
+}
+
+// SaveGeneratedFile stores a generated file in the database along with the model that wrote it
+func (s *Storage) SaveGeneratedFile(taskID, filePath, content, model string) error {
+	query := `
+		INSERT INTO files_generated (task_id, file_path, content, model, created_at)
+		VALUES (?, ?, ?, ?, ?)
 	`
-	_, err := s.db.Exec(query, taskID, filePath, content, time.Now())
+	_, err := s.db.Exec(query, taskID, filePath, content, model, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to save generated file: %w", err)
 	}
 	return nil
 }
 
+// RecordAttempt stores the outcome of a single LLM call for a task
+func (s *Storage) RecordAttempt(attempt Attempt) error {
+	query := `
+		INSERT INTO task_attempts (task_id, phase, attempt, status, error, options, model, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, attempt.TaskID, attempt.Phase, attempt.Attempt, attempt.Status,
+		attempt.Error, attempt.Options, attempt.Model, attempt.Duration.Milliseconds(), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record attempt: %w", err)
+	}
+	return nil
+}
+
+// GetAttempts retrieves all recorded LLM calls for a task in order
+func (s *Storage) GetAttempts(taskID string) ([]Attempt, error) {
+	query := `
+		SELECT id, task_id, phase, attempt, status, error, options, model, duration_ms, created_at
+		FROM task_attempts
+		WHERE task_id = ?
+		ORDER BY id ASC
+	`
+	rows, err := s.db.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []Attempt
+	for rows.Next() {
+		var attempt Attempt
+		var nullError, nullOptions, nullModel sql.NullString
+		var durationMs int64
+
+		err := rows.Scan(&attempt.ID, &attempt.TaskID, &attempt.Phase, &attempt.Attempt,
+			&attempt.Status, &nullError, &nullOptions, &nullModel, &durationMs, &attempt.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attempt: %w", err)
+		}
+
+		if nullError.Valid {
+			attempt.Error = nullError.String
+		}
+		if nullOptions.Valid {
+			attempt.Options = nullOptions.String
+		}
+		if nullModel.Valid {
+			attempt.Model = nullModel.String
+		}
+		attempt.Duration = time.Duration(durationMs) * time.Millisecond
+
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RecordTestResults stores the package and test outcomes of a go test run,
+// replacing any recorded earlier for the run (e.g. before it was resumed)
+func (s *Storage) RecordTestResults(runID string, packages, tests []TestResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"test_packages", "test_results"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE run_id = ?", runID); err != nil {
+			return fmt.Errorf("failed to delete previous test results: %w", err)
+		}
+	}
+
+	now := time.Now()
+	for _, p := range packages {
+		_, err := tx.Exec(`
+			INSERT INTO test_packages (run_id, package, status, output, duration_ms, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, runID, p.Package, p.Status, p.Output, p.Duration.Milliseconds(), now)
+		if err != nil {
+			return fmt.Errorf("failed to record package result: %w", err)
+		}
+	}
+	for _, t := range tests {
+		_, err := tx.Exec(`
+			INSERT INTO test_results (run_id, package, test, status, output, task_id, model, duration_ms, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, runID, t.Package, t.Test, t.Status, t.Output, t.TaskID, t.Model, t.Duration.Milliseconds(), now)
+		if err != nil {
+			return fmt.Errorf("failed to record test result: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetTestResults retrieves the test outcomes of a run in order
+func (s *Storage) GetTestResults(runID string) ([]TestResult, error) {
+	query := `
+		SELECT id, run_id, package, test, status, output, task_id, model, duration_ms, created_at
+		FROM test_results
+		WHERE run_id = ?
+		ORDER BY id ASC
+	`
+	rows, err := s.db.Query(query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query test results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []TestResult
+	for rows.Next() {
+		var result TestResult
+		var nullOutput, nullTaskID, nullModel sql.NullString
+		var durationMs int64
+
+		err := rows.Scan(&result.ID, &result.RunID, &result.Package, &result.Test, &result.Status,
+			&nullOutput, &nullTaskID, &nullModel, &durationMs, &result.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan test result: %w", err)
+		}
+
+		result.Output = nullOutput.String
+		result.TaskID = nullTaskID.String
+		result.Model = nullModel.String
+		result.Duration = time.Duration(durationMs) * time.Millisecond
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating test results: %w", err)
+	}
+
+	return results, nil
+}
+
+// GetCachedResponse looks up a cached LLM response by key
+// Entries created before since are treated as missing; a zero since accepts any age
+func (s *Storage) GetCachedResponse(key string, since time.Time) (string, bool, error) {
+	query := `
+		SELECT response, created_at
+		FROM llm_cache
+		WHERE key = ?
+	`
+	var response string
+	var createdAt time.Time
+
+	err := s.db.QueryRow(query, key).Scan(&response, &createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get cached response: %w", err)
+	}
+
+	if !since.IsZero() && createdAt.Before(since) {
+		return "", false, nil
+	}
+	return response, true, nil
+}
+
+// PutCachedResponse stores an LLM response, replacing any entry with the same key
+func (s *Storage) PutCachedResponse(entry CacheEntry) error {
+	query := `
+		INSERT OR REPLACE INTO llm_cache (key, model, response, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, entry.Key, entry.Model, entry.Response, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	return nil
+}
+
+// EvictCache deletes cached responses created before the given time
+// Returns the number of entries removed
+func (s *Storage) EvictCache(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM llm_cache WHERE created_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to evict cache: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // GetTask retrieves a task by ID
 func (s *Storage) GetTask(taskID string) (*Task, error) {
 	query := `
-		SELECT id, type, input, output, status, error, created_at, updated_at
+		SELECT id, type, input, output, status, error, model, created_at, updated_at
 		FROM tasks
 		WHERE id = ?
 	`
 	var task Task
-	var nullOutput, nullError sql.NullString
+	var nullOutput, nullError, nullModel sql.NullString
 
 	err := s.db.QueryRow(query, taskID).Scan(
 		&task.ID, &task.Type, &task.Input, &nullOutput,
-		&task.Status, &nullError, &task.CreatedAt, &task.UpdatedAt,
+		&task.Status, &nullError, &nullModel, &task.CreatedAt, &task.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -175,6 +559,9 @@ func (s *Storage) GetTask(taskID string) (*Task, error) {
 	if nullError.Valid {
 		task.Error = nullError.String
 	}
+	if nullModel.Valid {
+		task.Model = nullModel.String
+	}
 
 	return &task, nil
 }
@@ -182,11 +569,29 @@ func (s *Storage) GetTask(taskID string) (*Task, error) {
 // GetAllTasks retrieves all tasks from the database
 func (s *Storage) GetAllTasks() ([]Task, error) {
 	query := `
-		SELECT id, type, input, output, status, error, created_at, updated_at
+		SELECT id, type, input, output, status, error, model, created_at, updated_at
+		FROM tasks
+		ORDER BY created_at ASC
+	`
+	return s.queryTasks(query)
+}
+
+// GetRunTasks retrieves the tasks of a single run
+// Task IDs are prefixed with the run ID, e.g. run_1755633455_models
+func (s *Storage) GetRunTasks(runID string) ([]Task, error) {
+	query := `
+		SELECT id, type, input, output, status, error, model, created_at, updated_at
 		FROM tasks
+		WHERE substr(id, 1, ?) = ?
 		ORDER BY created_at ASC
 	`
-	rows, err := s.db.Query(query)
+	prefix := runID + "_"
+	return s.queryTasks(query, len(prefix), prefix)
+}
+
+// queryTasks runs a task query and scans every row
+func (s *Storage) queryTasks(query string, args ...interface{}) ([]Task, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tasks: %w", err)
 	}
@@ -195,11 +600,11 @@ func (s *Storage) GetAllTasks() ([]Task, error) {
 	var tasks []Task
 	for rows.Next() {
 		var task Task
-		var nullOutput, nullError sql.NullString
+		var nullOutput, nullError, nullModel sql.NullString
 
 		err := rows.Scan(
 			&task.ID, &task.Type, &task.Input, &nullOutput,
-			&task.Status, &nullError, &task.CreatedAt, &task.UpdatedAt,
+			&task.Status, &nullError, &nullModel, &task.CreatedAt, &task.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
@@ -212,6 +617,9 @@ func (s *Storage) GetAllTasks() ([]Task, error) {
 		if nullError.Valid {
 			task.Error = nullError.String
 		}
+		if nullModel.Valid {
+			task.Model = nullModel.String
+		}
 
 		tasks = append(tasks, task)
 	}
@@ -223,13 +631,40 @@ func (s *Storage) GetAllTasks() ([]Task, error) {
 	return tasks, nil
 }
 
+// GetFileTasks maps every recorded file path to the task that last generated it
+func (s *Storage) GetFileTasks() (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT file_path, task_id FROM files_generated ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query generated files: %w", err)
+	}
+	defer rows.Close()
+
+	owners := make(map[string]string)
+	for rows.Next() {
+		var filePath string
+		var taskID sql.NullString
+		if err := rows.Scan(&filePath, &taskID); err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		if taskID.Valid {
+			owners[filePath] = taskID.String
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating files: %w", err)
+	}
+
+	return owners, nil
+}
+
 // GetGeneratedFiles retrieves all files generated for a specific task
 func (s *Storage) GetGeneratedFiles(taskID string) ([]FileGenerated, error) {
 	query := `
-		SELECT id, task_id, file_path, content, created_at
+		SELECT id, task_id, file_path, content, model, created_at
 		FROM files_generated
 		WHERE task_id = ?
-		ORDER BY created_at ASC
+		ORDER BY id ASC
 	`
 	rows, err := s.db.Query(query, taskID)
 	if err != nil {
@@ -240,10 +675,14 @@ func (s *Storage) GetGeneratedFiles(taskID string) ([]FileGenerated, error) {
 	var files []FileGenerated
 	for rows.Next() {
 		var file FileGenerated
-		err := rows.Scan(&file.ID, &file.TaskID, &file.FilePath, &file.Content, &file.CreatedAt)
+		var nullModel sql.NullString
+		err := rows.Scan(&file.ID, &file.TaskID, &file.FilePath, &file.Content, &nullModel, &file.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan file: %w", err)
 		}
+		if nullModel.Valid {
+			file.Model = nullModel.String
+		}
 		files = append(files, file)
 	}
 
@@ -269,11 +708,29 @@ func (s *Storage) CleanAllTasks() error {
 		return fmt.Errorf("failed to delete generated files: %w", err)
 	}
 
+	// Delete attempt history for the same reason
+	if _, err := tx.Exec("DELETE FROM task_attempts"); err != nil {
+		return fmt.Errorf("failed to delete task attempts: %w", err)
+	}
+
+	// Delete test outcomes, which belong to runs
+	if _, err := tx.Exec("DELETE FROM test_results"); err != nil {
+		return fmt.Errorf("failed to delete test results: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM test_packages"); err != nil {
+		return fmt.Errorf("failed to delete test packages: %w", err)
+	}
+
 	// Delete all tasks
 	if _, err := tx.Exec("DELETE FROM tasks"); err != nil {
 		return fmt.Errorf("failed to delete tasks: %w", err)
 	}
 
+	// Delete all runs
+	if _, err := tx.Exec("DELETE FROM runs"); err != nil {
+		return fmt.Errorf("failed to delete runs: %w", err)
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// baselineSchema is the schema of databases created before runs, attempts
+// and per-model tracking were added
+const baselineSchema = `
+CREATE TABLE tasks (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    input TEXT,
+    output TEXT,
+    status TEXT NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE files_generated (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT,
+    file_path TEXT NOT NULL,
+    content TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks(id)
+);
+
+-- As first added, before generation options and fallback models were tracked
+CREATE TABLE task_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT NOT NULL,
+    phase TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    duration_ms INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks(id)
+);
+
+INSERT INTO tasks (id, type, input, status) VALUES ('old_task', 'models', 'prompt', 'completed');
+INSERT INTO files_generated (task_id, file_path, content) VALUES ('old_task', 'models/todo.go', 'package models');
+`
+
+// TestInitDBMigratesOldDatabase verifies a database created by an older
+// version gains the missing columns and keeps its data
+func TestInitDBMigratesOldDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "poc.db")
+
+	old, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(baselineSchema); err != nil {
+		t.Fatalf("Failed to create baseline schema: %v", err)
+	}
+	old.Close()
+
+	// Opening twice checks the migrations are idempotent
+	for i := 0; i < 2; i++ {
+		db, err := InitDB(dbPath)
+		if err != nil {
+			t.Fatalf("InitDB failed: %v", err)
+		}
+		db.Close()
+	}
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	s := NewStorage(db)
+	defer s.Close()
+
+	tasks, err := s.GetAllTasks()
+	if err != nil {
+		t.Fatalf("GetAllTasks failed: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "old_task" || tasks[0].Model != "" {
+		t.Errorf("Expected the old task without a model, got %+v", tasks)
+	}
+
+	if err := s.UpdateTaskModel("old_task", "codellama:7b"); err != nil {
+		t.Fatalf("UpdateTaskModel failed: %v", err)
+	}
+	if err := s.SaveGeneratedFile("old_task", "models/todo.go", "package models\n", "codellama:7b"); err != nil {
+		t.Fatalf("SaveGeneratedFile failed: %v", err)
+	}
+	files, err := s.GetGeneratedFiles("old_task")
+	if err != nil {
+		t.Fatalf("GetGeneratedFiles failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Model != "" || files[1].Model != "codellama:7b" {
+		t.Errorf("Expected the old and new file versions, got %+v", files)
+	}
+
+	if err := s.RecordAttempt(Attempt{TaskID: "old_task", Phase: "generate", Attempt: 1, Status: "success", Options: `{"temperature":0.2}`, Model: "codellama:7b"}); err != nil {
+		t.Fatalf("RecordAttempt failed: %v", err)
+	}
+	attempts, err := s.GetAttempts("old_task")
+	if err != nil {
+		t.Fatalf("GetAttempts failed: %v", err)
+	}
+	if len(attempts) != 1 || attempts[0].Model != "codellama:7b" || attempts[0].Options == "" {
+		t.Errorf("Expected the attempt with its options and model, got %+v", attempts)
+	}
+}
@@ -0,0 +1,347 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Checker parses and type-checks generated Go code in-process
+// Unlike Validator it needs no Go toolchain in PATH. Imports it can't resolve
+// (third-party modules, or the standard library without GOROOT sources) are
+// replaced by empty packages, and errors about their members are ignored
+type Checker struct {
+	workDir string
+
+	mu  sync.Mutex     // The source importer is not safe for concurrent use
+	std types.Importer // Standard library packages, shared by every check
+}
+
+// NewChecker creates an in-process checker for the module in workDir
+func NewChecker(workDir string) *Checker {
+	return &Checker{
+		workDir: workDir,
+		std:     importer.ForCompiler(token.NewFileSet(), "source", nil),
+	}
+}
+
+// undefinedPattern matches go/types errors about missing package members
+var undefinedPattern = regexp.MustCompile(`^undefined: (\w+)\.\w+$`)
+
+// Check parses and type-checks the packages containing the given files
+// overlay maps paths relative to the work directory to source that replaces or
+// adds to what is on disk, so code can be checked before it is written. With
+// no overlay every package in the work directory is checked
+func (c *Checker) Check(overlay map[string]string) []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &checkSession{
+		Checker:  c,
+		fset:     token.NewFileSet(),
+		overlay:  make(map[string]string, len(overlay)),
+		module:   readModulePath(c.workDir),
+		packages: make(map[string]*types.Package),
+		fakes:    make(map[string]bool),
+		seen:     make(map[string]bool),
+	}
+	for file, src := range overlay {
+		s.overlay[path.Clean(filepath.ToSlash(file))] = src
+	}
+
+	var dirs []string
+	if len(overlay) > 0 {
+		for file := range s.overlay {
+			dirs = append(dirs, path.Dir(file))
+		}
+	} else {
+		dirs = s.packageDirs()
+	}
+	sort.Strings(dirs)
+
+	checked := make(map[string]bool)
+	for _, dir := range dirs {
+		if !checked[dir] {
+			checked[dir] = true
+			s.checkDir(dir)
+		}
+	}
+
+	sort.SliceStable(s.diags, func(i, j int) bool {
+		if s.diags[i].File != s.diags[j].File {
+			return s.diags[i].File < s.diags[j].File
+		}
+		return s.diags[i].Line < s.diags[j].Line
+	})
+	return s.diags
+}
+
+// checkSession holds the state of a single Check call
+type checkSession struct {
+	*Checker
+	fset     *token.FileSet
+	overlay  map[string]string
+	module   string
+	packages map[string]*types.Package // Module packages by import path; nil while loading
+	fakes    map[string]bool           // Names of packages replaced by empty ones
+	seen     map[string]bool           // Reported diagnostics, to drop duplicates
+	diags    []Diagnostic
+}
+
+// checkDir type-checks the package in dir together with its tests
+func (s *checkSession) checkDir(dir string) {
+	files, ok := s.parseDir(dir)
+	if !ok {
+		return
+	}
+
+	// In-package tests are checked with the package; external tests separately
+	var pkgFiles, externalTests []*ast.File
+	for _, f := range files {
+		if strings.HasSuffix(f.Name.Name, "_test") {
+			externalTests = append(externalTests, f)
+		} else {
+			pkgFiles = append(pkgFiles, f)
+		}
+	}
+
+	importPath := s.importPath(dir)
+	if len(pkgFiles) > 0 {
+		s.typeCheck(importPath, pkgFiles)
+	}
+	if len(externalTests) > 0 {
+		s.typeCheck(importPath+"_test", externalTests)
+	}
+}
+
+// parseDir parses every Go file of a directory, taking overlay files over disk
+// ok is false if the directory has syntax errors, which are reported instead
+func (s *checkSession) parseDir(dir string) (files []*ast.File, ok bool) {
+	sources := make(map[string]string)
+	if entries, err := os.ReadDir(filepath.Join(s.workDir, filepath.FromSlash(dir))); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+				continue
+			}
+			name := path.Join(dir, entry.Name())
+			data, err := os.ReadFile(filepath.Join(s.workDir, filepath.FromSlash(name)))
+			if err != nil {
+				continue
+			}
+			sources[name] = string(data)
+		}
+	}
+	for name, src := range s.overlay {
+		if path.Dir(name) == dir && strings.HasSuffix(name, ".go") {
+			sources[name] = src
+		}
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ok = true
+	for _, name := range names {
+		f, err := parser.ParseFile(s.fset, name, sources[name], parser.AllErrors|parser.SkipObjectResolution)
+		if err != nil {
+			ok = false
+			s.addParseError(err)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, ok
+}
+
+// typeCheck checks a package and records its errors
+func (s *checkSession) typeCheck(importPath string, files []*ast.File) *types.Package {
+	conf := types.Config{
+		Importer: s,
+		Error: func(err error) {
+			var typeErr types.Error
+			if errors.As(err, &typeErr) {
+				s.addTypeError(typeErr)
+			}
+		},
+	}
+	pkg, _ := conf.Check(importPath, s.fset, files, nil)
+	return pkg
+}
+
+// Import resolves an import for the type checker
+// Packages of the generated module are checked from source, the standard
+// library comes from GOROOT, and anything else is an empty stand-in
+func (s *checkSession) Import(importPath string) (*types.Package, error) {
+	if s.module != "" && (importPath == s.module || strings.HasPrefix(importPath, s.module+"/")) {
+		return s.importModulePackage(importPath)
+	}
+
+	if first, _, _ := strings.Cut(importPath, "/"); !strings.Contains(first, ".") {
+		if pkg, err := s.std.Import(importPath); err == nil {
+			return pkg, nil
+		}
+	}
+	return s.fake(importPath), nil
+}
+
+// importModulePackage loads a package of the generated module
+func (s *checkSession) importModulePackage(importPath string) (*types.Package, error) {
+	if pkg, ok := s.packages[importPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", importPath)
+		}
+		return pkg, nil
+	}
+	s.packages[importPath] = nil
+
+	dir := strings.TrimPrefix(strings.TrimPrefix(importPath, s.module), "/")
+	if dir == "" {
+		dir = "."
+	}
+
+	files, _ := s.parseDir(dir)
+	var pkgFiles []*ast.File
+	for _, f := range files {
+		if !strings.HasSuffix(s.fset.File(f.Pos()).Name(), "_test.go") {
+			pkgFiles = append(pkgFiles, f)
+		}
+	}
+	if len(pkgFiles) == 0 {
+		return nil, fmt.Errorf("no Go files for %s", importPath)
+	}
+
+	pkg := s.typeCheck(importPath, pkgFiles)
+	s.packages[importPath] = pkg
+	return pkg, nil
+}
+
+// fake returns an empty, complete package standing in for an unresolvable import
+func (s *checkSession) fake(importPath string) *types.Package {
+	name := path.Base(importPath)
+	if strings.HasPrefix(name, "v") && strings.Trim(name[1:], "0123456789") == "" && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath)) // Major version suffix, e.g. ".../v2"
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "-go")
+	name = strings.ReplaceAll(name, "-", "")
+
+	pkg := types.NewPackage(importPath, name)
+	pkg.MarkComplete()
+	s.fakes[name] = true
+	return pkg
+}
+
+// addParseError records the errors of a file that failed to parse
+func (s *checkSession) addParseError(err error) {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return
+	}
+	for _, e := range list {
+		s.add(Diagnostic{
+			File:     e.Pos.Filename,
+			Line:     e.Pos.Line,
+			Column:   e.Pos.Column,
+			Severity: SeverityError,
+			Tool:     ToolParser,
+			Message:  e.Msg,
+		})
+	}
+}
+
+// addTypeError records a type error unless it is about a stand-in package
+func (s *checkSession) addTypeError(err types.Error) {
+	if match := undefinedPattern.FindStringSubmatch(err.Msg); match != nil && s.fakes[match[1]] {
+		return
+	}
+	pos := err.Fset.Position(err.Pos)
+	s.add(Diagnostic{
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: SeverityError,
+		Tool:     ToolTypes,
+		Message:  err.Msg,
+	})
+}
+
+// add records a diagnostic once
+func (s *checkSession) add(d Diagnostic) {
+	key := d.String()
+	if s.seen[key] {
+		return
+	}
+	s.seen[key] = true
+	s.diags = append(s.diags, d)
+}
+
+// importPath returns the import path of a directory of the work directory
+func (s *checkSession) importPath(dir string) string {
+	if dir == "." {
+		return s.module
+	}
+	return s.module + "/" + dir
+}
+
+// packageDirs lists every directory of the work directory holding Go files
+func (s *checkSession) packageDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	filepath.WalkDir(s.workDir, func(p string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			// Skip hidden directories and testdata like the go command does
+			name := entry.Name()
+			if p != s.workDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.workDir, filepath.Dir(p))
+		if err != nil {
+			return nil
+		}
+		dir := filepath.ToSlash(rel)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// readModulePath returns the module path declared in workDir's go.mod, if any
+func readModulePath(workDir string) string {
+	data, err := os.ReadFile(filepath.Join(workDir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
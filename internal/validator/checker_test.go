@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a module in a temp directory from relative paths and contents
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	workDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return workDir
+}
+
+// TestCheckerValidModule verifies a correct module produces no diagnostics
+func TestCheckerValidModule(t *testing.T) {
+	workDir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/todo\n\ngo 1.21\n",
+		"internal/models/todo.go": `package models
+
+import "strings"
+
+// Todo is a single todo item
+type Todo struct{ Title string }
+
+// Normalize trims the title
+func (t *Todo) Normalize() { t.Title = strings.TrimSpace(t.Title) }
+`,
+		"main.go": `package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	_ "github.com/mattn/go-sqlite3"
+	"example.com/todo/internal/models"
+)
+
+func main() {
+	var db *sql.DB
+	todo := models.Todo{Title: " x "}
+	todo.Normalize()
+	fmt.Println(todo.Title, db == nil, http.StatusOK)
+}
+`,
+	})
+
+	if diags := NewChecker(workDir).Check(nil); len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diags)
+	}
+}
+
+// TestCheckerReportsErrors verifies syntax and type errors carry their position
+func TestCheckerReportsErrors(t *testing.T) {
+	workDir := writeModule(t, map[string]string{
+		"go.mod":                  "module example.com/todo\n",
+		"internal/models/todo.go": "package models\n\ntype Todo struct{ Title string }\n",
+	})
+	checker := NewChecker(workDir)
+
+	// Type errors against another package of the module, checked before writing
+	diags := checker.Check(map[string]string{
+		"internal/handlers/todo.go": "package handlers\n\nimport \"example.com/todo/internal/models\"\n\nfunc Title(t models.Todo) int {\n\treturn t.Name\n}\n",
+	})
+	if len(diags) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diags)
+	}
+	d := diags[0]
+	if d.File != "internal/handlers/todo.go" || d.Line != 6 || d.Column == 0 || !strings.Contains(d.Message, "Name") {
+		t.Errorf("Unexpected diagnostic: %+v", d)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "internal", "handlers")); err == nil {
+		t.Error("Checking an overlay should not write it")
+	}
+
+	// Syntax errors are reported without type checking
+	diags = checker.Check(map[string]string{"main.go": "package main\n\nfunc main() {\n\tx :=\n}\n"})
+	if len(diags) == 0 || diags[0].File != "main.go" || diags[0].Line == 0 {
+		t.Errorf("Expected a syntax error in main.go, got %v", diags)
+	}
+}
+
+// TestCheckerUnresolvedImports verifies stand-in packages don't cause errors
+func TestCheckerUnresolvedImports(t *testing.T) {
+	workDir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/app\n",
+		"main.go": `package main
+
+import "github.com/gorilla/mux"
+
+func main() {
+	r := mux.NewRouter()
+	_ = r
+}
+`,
+	})
+
+	if diags := NewChecker(workDir).Check(nil); len(diags) != 0 {
+		t.Errorf("Expected errors about unresolved packages to be ignored, got %v", diags)
+	}
+}
@@ -0,0 +1,116 @@
+package validator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Tools reporting diagnostics besides the go commands named in ValidationResult.Tool
+const (
+	ToolParser = "go/parser"
+	ToolTypes  = "go/types"
+)
+
+// Diagnostic is a problem found in a Go source file
+type Diagnostic struct {
+	File     string `json:"file"` // Path relative to the work directory, slash-separated
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Tool     string `json:"tool"`
+	Message  string `json:"message"`
+	TaskID   string `json:"task_id,omitempty"` // Task that generated File, filled in by the orchestrator
+}
+
+// String formats the diagnostic like the Go toolchain does
+func (d Diagnostic) String() string {
+	switch {
+	case d.Line == 0:
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	case d.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// diagnosticPattern matches "file.go:line:col: message" lines from the go commands
+// go vet prefixes type errors of packages it can't analyse with "vet: "
+var diagnosticPattern = regexp.MustCompile(`^(vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+// ParseDiagnostics extracts file-level diagnostics from the output of a tool
+// File paths are made relative to workDir. go vet findings are warnings
+// unless vet couldn't type-check the package; everything else is an error
+func ParseDiagnostics(tool, output, workDir string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		match := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		severity := SeverityError
+		if tool == "go vet" && match[1] == "" {
+			severity = SeverityWarning
+		}
+
+		lineNum, _ := strconv.Atoi(match[3])
+		column, _ := strconv.Atoi(match[4])
+		diags = append(diags, Diagnostic{
+			File:     relativePath(match[2], workDir),
+			Line:     lineNum,
+			Column:   column,
+			Severity: severity,
+			Tool:     tool,
+			Message:  match[5],
+		})
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		return diags[i].Line < diags[j].Line
+	})
+	return diags
+}
+
+// parseUnformatted turns the file list printed by gofmt -l into warnings
+func parseUnformatted(output, workDir string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		file := strings.TrimSpace(line)
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			File:     relativePath(file, workDir),
+			Severity: SeverityWarning,
+			Tool:     "gofmt",
+			Message:  "file is not gofmt-formatted",
+		})
+	}
+	return diags
+}
+
+// relativePath makes a path reported by a tool relative to workDir, slash-separated
+// Tools run in workDir report relative paths already; gofmt reports paths
+// joined to workDir, and some tools report absolute ones
+func relativePath(file, workDir string) string {
+	file = filepath.Clean(file)
+	if filepath.IsAbs(file) || strings.HasPrefix(file, filepath.Clean(workDir)+string(filepath.Separator)) {
+		if rel, err := filepath.Rel(workDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(file)
+}
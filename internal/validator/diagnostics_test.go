@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestParseDiagnostics verifies go build and go vet output parsing
+func TestParseDiagnostics(t *testing.T) {
+	workDir := "/tmp/generated"
+	output := `# todo-api/internal/models
+internal/models/todo.go:5:25: undefined: errors
+internal/models/todo.go:3:8: "database/sql" imported and not used
+vet: internal/handlers/todo_handler.go:10:2: undefined: mux
+/tmp/generated/internal/repository/todo_repo.go:7: missing return
+note: module requires Go 1.21`
+
+	diags := ParseDiagnostics("go build", output, workDir)
+	if len(diags) != 4 {
+		t.Fatalf("Expected 4 diagnostics, got %d: %+v", len(diags), diags)
+	}
+
+	// Results are sorted by file then line
+	first := diags[0]
+	if first.File != "internal/handlers/todo_handler.go" || first.Line != 10 || first.Column != 2 {
+		t.Errorf("Unexpected first diagnostic: %+v", first)
+	}
+
+	if diags[1].File != "internal/models/todo.go" || diags[1].Line != 3 {
+		t.Errorf("Expected models line 3 second, got %+v", diags[1])
+	}
+	if diags[1].Tool != "go build" || diags[1].Severity != SeverityError {
+		t.Errorf("Expected a go build error, got %+v", diags[1])
+	}
+
+	// Absolute paths are made relative to the work directory
+	last := diags[3]
+	if last.File != "internal/repository/todo_repo.go" || last.Column != 0 || last.Message != "missing return" {
+		t.Errorf("Unexpected absolute path diagnostic: %+v", last)
+	}
+	if last.String() != "internal/repository/todo_repo.go:7: missing return" {
+		t.Errorf("Unexpected formatting: %s", last)
+	}
+}
+
+// TestParseDiagnosticsSeverity verifies vet findings are warnings unless vet failed to type-check
+func TestParseDiagnosticsSeverity(t *testing.T) {
+	output := "main.go:12:2: fmt.Printf format %d has arg s of wrong type string\nvet: models.go:3:8: undefined: errors"
+
+	diags := ParseDiagnostics("go vet", output, "/tmp/generated")
+	if len(diags) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", diags)
+	}
+	if diags[0].File != "main.go" || diags[0].Severity != SeverityWarning {
+		t.Errorf("Expected a vet warning for main.go, got %+v", diags[0])
+	}
+	if diags[1].File != "models.go" || diags[1].Severity != SeverityError {
+		t.Errorf("Expected a type error for models.go, got %+v", diags[1])
+	}
+}
+
+// TestParseUnformatted verifies gofmt -l output becomes per-file warnings
+func TestParseUnformatted(t *testing.T) {
+	workDir := filepath.Join("out", "generated")
+	output := filepath.Join(workDir, "main.go") + "\n" + filepath.Join(workDir, "internal", "models", "todo.go") + "\n"
+
+	diags := parseUnformatted(output, workDir)
+	if len(diags) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", diags)
+	}
+	if diags[1].File != "internal/models/todo.go" || diags[1].Tool != "gofmt" || diags[1].Severity != SeverityWarning {
+		t.Errorf("Unexpected diagnostic: %+v", diags[1])
+	}
+}
@@ -0,0 +1,248 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// SandboxLimits bounds the resources of every sandboxed process
+// Zero values leave a resource unlimited
+type SandboxLimits struct {
+	CPUTime   time.Duration // RLIMIT_CPU; wall time is bounded by the validator's timeouts
+	Memory    uint64        // RLIMIT_AS, in bytes
+	FileSize  uint64        // RLIMIT_FSIZE, in bytes
+	OpenFiles uint64        // RLIMIT_NOFILE
+}
+
+// DefaultSandboxLimits leave room for compiling cgo dependencies such as go-sqlite3
+var DefaultSandboxLimits = SandboxLimits{
+	CPUTime:   5 * time.Minute,
+	Memory:    4 << 30,
+	FileSize:  1 << 30,
+	OpenFiles: 1024,
+}
+
+// sandboxEnv carries a sandboxSpec to the re-executed binary inside the sandbox
+const sandboxEnv = "GORCHESTRATOR_SANDBOX"
+
+// sandboxSpec tells the re-executed binary what to run inside the sandbox
+type sandboxSpec struct {
+	Limits   SandboxLimits
+	Writable []string   // Directories left writable; the rest of the filesystem is read-only
+	Args     []string   // Command to run; none only checks the sandbox works
+	Smoke    *smokeSpec // Run the smoke scenario against Args as the server instead
+}
+
+// sandboxConfig is the setup of an enabled sandbox
+type sandboxConfig struct {
+	limits     SandboxLimits
+	goRoot     string // The host's Go toolchain, read-only in the sandbox
+	goModCache string // The host's module cache, read-only in the sandbox
+	goCache    string // Build cache used only by sandboxed commands
+}
+
+// smokeSpec describes a smoke test run inside the sandbox
+type smokeSpec struct {
+	Endpoints []Endpoint
+	Dir       string // Working directory and database location of the server
+	Port      int
+	Timeout   time.Duration
+}
+
+// smokeReport is the outcome of a smoke test, written as JSON by the sandbox
+type smokeReport struct {
+	Log          []string
+	Error        string
+	ServerOutput string
+}
+
+// EnableSandbox runs the Go toolchain, generated tests and the smoke-test
+// server in a sandbox: a scratch copy of the work directory, with limits on
+// CPU, memory, file size and open files, and no network access. Everything
+// but the scratch directory and a build cache of the sandbox's own is
+// read-only, and the host's environment is not passed on. Module dependencies
+// are downloaded on the host first, so the module cache stays read-only.
+// Only Linux is supported
+func (v *Validator) EnableSandbox(limits SandboxLimits) error {
+	config, err := newSandboxConfig(limits)
+	if err != nil {
+		return fmt.Errorf("sandbox unavailable: %w", err)
+	}
+	if err := checkSandbox(); err != nil {
+		return fmt.Errorf("sandbox unavailable: %w", err)
+	}
+	v.sandbox = config
+	return nil
+}
+
+// newSandboxConfig looks up the host's Go toolchain and module cache, and
+// creates the sandbox's own build cache. Sandboxed code can write to that
+// cache, so the host's builds never use it; it is kept between runs since
+// compiling cgo dependencies such as go-sqlite3 takes longer than a build's
+// timeout
+func newSandboxConfig(limits SandboxLimits) (*sandboxConfig, error) {
+	out, err := exec.Command("go", "env", "-json", "GOROOT", "GOMODCACHE").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the Go toolchain: %w", err)
+	}
+	var env map[string]string
+	if err := json.Unmarshal(out, &env); err != nil {
+		return nil, fmt.Errorf("failed to read go env: %w", err)
+	}
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the user cache directory: %w", err)
+	}
+
+	config := &sandboxConfig{
+		limits:     limits,
+		goRoot:     env["GOROOT"],
+		goModCache: env["GOMODCACHE"],
+		goCache:    filepath.Join(userCache, "gorchestrator", "sandbox-go-build"),
+	}
+	for _, dir := range []string{config.goModCache, config.goCache} {
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("go cache %q is not an absolute path", dir)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create go cache: %w", err)
+		}
+	}
+	return config, nil
+}
+
+// prepare returns the directory commands on the generated code run in
+// Sandboxed commands get a copy of the work directory in a scratch directory,
+// which cleanup removes; otherwise it is the work directory itself
+func (v *Validator) prepare(ctx context.Context) (dir string, cleanup func(), err error) {
+	if v.sandbox == nil {
+		return v.workDir, func() {}, nil
+	}
+
+	// Fetch dependencies while the network is still available; the module
+	// cache is read-only in the sandbox
+	if _, err := os.Stat(filepath.Join(v.workDir, "go.mod")); err == nil {
+		download := exec.CommandContext(ctx, "go", "mod", "download")
+		download.Dir = v.workDir
+		download.Run() // Ignore errors, the build will report missing deps
+	}
+
+	// The scratch directory holds the copy in work/ and temporary files in tmp/
+	scratch, err := os.MkdirTemp("", "sandbox-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(scratch) }
+	dir = filepath.Join(scratch, "work")
+	if err := os.Mkdir(filepath.Join(scratch, "tmp"), 0700); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	if err := copyTree(v.workDir, dir); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy work directory: %w", err)
+	}
+	return dir, cleanup, nil
+}
+
+// tempDir returns where commands on dir, as returned by prepare, can create
+// temporary files: the scratch directory's tmp/ when sandboxed, otherwise
+// the default temporary directory ("")
+func (v *Validator) tempDir(dir string) string {
+	if v.sandbox == nil {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(dir), "tmp")
+}
+
+// command creates a command that runs in dir, inside the sandbox if enabled
+func (v *Validator) command(ctx context.Context, dir string, name string, args ...string) (*exec.Cmd, error) {
+	if v.sandbox == nil {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		return cmd, nil
+	}
+	return v.sandboxed(ctx, dir, dir, sandboxSpec{Args: append([]string{name}, args...)})
+}
+
+// sandboxed creates a command running spec in the sandbox from cwd, where
+// only the scratch directory of dir (as returned by prepare) and the
+// sandbox's build cache are writable
+func (v *Validator) sandboxed(ctx context.Context, dir, cwd string, spec sandboxSpec) (*exec.Cmd, error) {
+	scratch := filepath.Dir(dir)
+	spec.Limits = v.sandbox.limits
+	spec.Writable = []string{scratch, v.sandbox.goCache}
+	return sandboxCommand(ctx, cwd, v.sandbox.environ(scratch), spec)
+}
+
+// environ is the environment of sandboxed processes. Only PATH, GOROOT and
+// GOMODCACHE are taken from the host, so secrets such as API keys stay
+// outside; the module proxy and toolchain downloads are disabled since there
+// is no network
+func (c *sandboxConfig) environ(scratch string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + scratch,
+		"TMPDIR=" + filepath.Join(scratch, "tmp"),
+		"GOROOT=" + c.goRoot,
+		"GOCACHE=" + c.goCache,
+		"GOMODCACHE=" + c.goModCache,
+		"GOPROXY=off",
+		"GOFLAGS=-mod=mod",
+		"GOTOOLCHAIN=local",
+	}
+}
+
+// copyTree copies the regular files and directories under src into dst
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" && p != src {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(p, target, info.Mode().Perm())
+	})
+}
+
+// copyFile copies a single file with the given permissions
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,206 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// SandboxMain runs the sandbox helper and exits if this process was started
+// as one, and returns otherwise. Sandboxed commands re-execute the current
+// binary in fresh namespaces, where the helper sets up the sandbox from the
+// inside before running the command, so any binary enabling the sandbox
+// (the generator, tests of this package) must call it first thing in main
+// or TestMain
+func SandboxMain() {
+	if spec := os.Getenv(sandboxEnv); spec != "" {
+		os.Exit(runSandbox(spec))
+	}
+	helperReady = true
+}
+
+// helperReady is set once SandboxMain has run, so a binary that doesn't call
+// it fails instead of re-executing its whole program
+var helperReady bool
+
+// checkSandbox verifies namespaces and mounts can be set up by running an empty spec
+func checkSandbox() error {
+	if !helperReady {
+		return fmt.Errorf("validator.SandboxMain is not called by this binary")
+	}
+	cmd, err := sandboxCommand(context.Background(), os.TempDir(), []string{"PATH=" + os.Getenv("PATH")}, sandboxSpec{})
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// sandboxCommand creates a command re-executing this binary as the sandbox
+// helper for spec, with only env as its environment. The helper gets its own
+// user, mount, network, PID, IPC and UTS namespaces, mapped to the caller's
+// user and group as root within them
+func sandboxCommand(ctx context.Context, dir string, env []string, spec sandboxSpec) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %w", err)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sandbox spec: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, self)
+	cmd.Dir = dir
+	cmd.Env = append(env[:len(env):len(env)], sandboxEnv+"="+string(data))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	return cmd, nil
+}
+
+// runSandbox runs inside the sandbox: it brings up the loopback interface,
+// makes the filesystem read-only, applies the resource limits and then runs
+// the command or smoke test
+func runSandbox(specJSON string) int {
+	os.Unsetenv(sandboxEnv)
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(specJSON), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: invalid spec: %v\n", err)
+		return 125
+	}
+	if err := loopbackUp(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: failed to bring up loopback: %v\n", err)
+		return 125
+	}
+	if err := isolateFilesystem(spec.Writable); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: failed to isolate filesystem: %v\n", err)
+		return 125
+	}
+	if err := setLimits(spec.Limits); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: failed to set limits: %v\n", err)
+		return 125
+	}
+
+	if len(spec.Args) == 0 {
+		return 0
+	}
+
+	if spec.Smoke != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), spec.Smoke.Timeout)
+		defer cancel()
+		report := runSmokeServer(ctx, spec.Args[0], spec.Smoke.Dir, spec.Smoke.Port, spec.Smoke.Endpoints)
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			return 125
+		}
+		return 0
+	}
+
+	path, err := exec.LookPath(spec.Args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return 127
+	}
+	err = syscall.Exec(path, spec.Args, os.Environ())
+	fmt.Fprintf(os.Stderr, "sandbox: failed to run %s: %v\n", spec.Args[0], err)
+	return 126
+}
+
+// loopbackUp brings up the loopback interface of the new network namespace,
+// which starts down, so the smoke-test server can listen on 127.0.0.1
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	// struct ifreq: interface name followed by a 24-byte union holding the flags
+	var req struct {
+		Name  [syscall.IFNAMSIZ]byte
+		Flags uint16
+		_     [22]byte
+	}
+	copy(req.Name[:], "lo")
+	req.Flags = syscall.IFF_UP | syscall.IFF_LOOPBACK | syscall.IFF_RUNNING
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isolateFilesystem makes every mount read-only, except bind mounts of the
+// writable directories, and mounts a /proc showing only the sandbox's own
+// processes so the host's (and their environments) can't be read
+func isolateFilesystem(writable []string) error {
+	// Mount changes must not propagate back to the host
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+
+	// The working directory still refers to the mount underneath the bind
+	// mounts, so it is entered again afterwards
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if err := mountSetattr("/", unix.MOUNT_ATTR_RDONLY, 0); err != nil {
+		return fmt.Errorf("failed to make / read-only: %w", err)
+	}
+	for _, dir := range writable {
+		if dir == "" {
+			continue
+		}
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", dir, err)
+		}
+		if err := mountSetattr(dir, 0, unix.MOUNT_ATTR_RDONLY); err != nil {
+			return fmt.Errorf("failed to make %s writable: %w", dir, err)
+		}
+	}
+	return os.Chdir(cwd)
+}
+
+// mountSetattr sets and clears attributes of the mount at path and every
+// mount below it
+func mountSetattr(path string, set, clear uint64) error {
+	return unix.MountSetattr(unix.AT_FDCWD, path, unix.AT_RECURSIVE, &unix.MountAttr{Attr_set: set, Attr_clr: clear})
+}
+
+// setLimits applies the resource limits to this process and its children
+func setLimits(limits SandboxLimits) error {
+	for _, limit := range []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, uint64(limits.CPUTime.Seconds())},
+		{syscall.RLIMIT_AS, limits.Memory},
+		{syscall.RLIMIT_FSIZE, limits.FileSize},
+		{syscall.RLIMIT_NOFILE, limits.OpenFiles},
+	} {
+		if limit.value == 0 {
+			continue
+		}
+		if err := syscall.Setrlimit(limit.resource, &syscall.Rlimit{Cur: limit.value, Max: limit.value}); err != nil {
+			return fmt.Errorf("resource %d: %w", limit.resource, err)
+		}
+	}
+	return nil
+}
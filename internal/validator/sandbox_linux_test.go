@@ -0,0 +1,215 @@
+package validator
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as the sandbox helper
+func TestMain(m *testing.M) {
+	SandboxMain()
+	os.Exit(m.Run())
+}
+
+// sandboxedValidator returns a sandboxed validator, skipping the test where
+// namespaces or the go toolchain aren't available
+func sandboxedValidator(t *testing.T, workDir string, limits SandboxLimits) *Validator {
+	t.Helper()
+	v := NewValidator(workDir)
+	if err := v.CheckGoInstallation(); err != nil {
+		t.Skip("go not available on test system")
+	}
+	if err := v.EnableSandbox(limits); err != nil {
+		t.Skipf("sandbox not available: %v", err)
+	}
+	return v
+}
+
+// TestSandboxRunTests verifies generated tests run with limits, without
+// network access and in a scratch copy of the work directory
+func TestSandboxRunTests(t *testing.T) {
+	// A host listener the generated test must not be able to reach
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+	addr := l.Addr().String()
+
+	workDir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/todo\n\ngo 1.21\n",
+		"sandbox_test.go": `package todo
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNoNetwork(t *testing.T) {
+	if conn, err := net.DialTimeout("tcp", "` + addr + `", time.Second); err == nil {
+		conn.Close()
+		t.Error("reached the host network")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	var lim syscall.Rlimit
+	syscall.Getrlimit(syscall.RLIMIT_CPU, &lim)
+	if lim.Max != 600 {
+		t.Errorf("expected a CPU limit of 600s, got %d", lim.Max)
+	}
+}
+
+func TestScratchCopy(t *testing.T) {
+	if err := os.WriteFile("written.txt", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+`,
+	})
+
+	limits := DefaultSandboxLimits
+	limits.CPUTime = 10 * time.Minute
+	v := sandboxedValidator(t, workDir, limits)
+
+	result := v.RunTests(context.Background())
+	if !result.Success {
+		t.Fatalf("Expected sandboxed tests to pass, got %v\n%s", result.Error, result.Output)
+	}
+	if result.Tests == nil || result.Tests.Count(TestPass) != 3 {
+		t.Errorf("Expected 3 passing tests, got %+v", result.Tests)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "written.txt")); err == nil {
+		t.Error("Sandboxed tests should not write to the work directory")
+	}
+}
+
+// TestSandboxBuildDiagnostics verifies diagnostics from the scratch copy point into the work directory
+func TestSandboxBuildDiagnostics(t *testing.T) {
+	workDir := writeModule(t, map[string]string{
+		"go.mod":    "module example.com/todo\n\ngo 1.21\n",
+		"models.go": "package todo\n\nvar count int = \"one\"\n",
+	})
+	v := sandboxedValidator(t, workDir, DefaultSandboxLimits)
+
+	result := v.tryBuild(context.Background())
+	if result.Success || len(result.Diagnostics) == 0 {
+		t.Fatalf("Expected a build error, got %+v", result)
+	}
+	if d := result.Diagnostics[0]; d.File != "models.go" || d.Line != 3 {
+		t.Errorf("Unexpected diagnostic: %+v", d)
+	}
+}
+
+// TestSandboxSmokeTest verifies the smoke test reaches a server in the sandbox's own network
+func TestSandboxSmokeTest(t *testing.T) {
+	workDir := writeModule(t, map[string]string{
+		"go.mod":             "module example.com/todo\n\ngo 1.21\n",
+		"README.md":          smokeReadme,
+		"cmd/server/main.go": strings.Replace(smokeServer, "CREATED", "http.StatusCreated", 1),
+	})
+	v := sandboxedValidator(t, workDir, DefaultSandboxLimits)
+
+	result := v.SmokeTest(context.Background())
+	if !result.Success {
+		t.Fatalf("Expected the sandboxed smoke test to pass, got %v\n%s", result.Error, result.Output)
+	}
+	if !strings.Contains(result.Output, "PASS DELETE /todos/1 -> "+strconv.Itoa(204)) {
+		t.Errorf("Unexpected output:\n%s", result.Output)
+	}
+}
+
+// TestSandboxIsolation verifies sandboxed code can only write to its scratch
+// directory and sees none of the host's environment
+func TestSandboxIsolation(t *testing.T) {
+	outside := t.TempDir()
+	t.Setenv("OPENAI_API_KEY", "sk-sandbox-secret")
+	hostCache, err := exec.Command("go", "env", "GOCACHE").Output()
+	if err != nil {
+		t.Skipf("go env failed: %v", err)
+	}
+
+	workDir := writeModule(t, map[string]string{
+		"go.mod":  "module example.com/todo\n\ngo 1.21\n",
+		"todo.go": "package todo\n\nfunc Add(a, b int) int { return a + b }\n",
+		"isolation_test.go": `package todo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fail()
+	}
+}
+
+func TestNoHostWrites(t *testing.T) {
+	if err := os.WriteFile(filepath.Join("` + outside + `", "leak.txt"), []byte("x"), 0644); err == nil {
+		t.Error("wrote outside the scratch directory")
+	}
+	if err := os.WriteFile(filepath.Join(os.TempDir(), "scratch.txt"), []byte("x"), 0644); err != nil {
+		t.Errorf("expected a writable temp directory: %v", err)
+	}
+}
+
+func TestGoCaches(t *testing.T) {
+	if err := os.WriteFile(filepath.Join(os.Getenv("GOMODCACHE"), "leak.txt"), []byte("x"), 0644); err == nil {
+		t.Error("wrote to the module cache")
+	}
+	if cache := os.Getenv("GOCACHE"); cache == "" || cache == "` + strings.TrimSpace(string(hostCache)) + `" {
+		t.Errorf("expected a build cache of the sandbox's own, got %q", cache)
+	}
+}
+
+func TestNoSecrets(t *testing.T) {
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		t.Errorf("secret visible in the environment: %s", key)
+	}
+	environs, _ := filepath.Glob("/proc/*/environ")
+	for _, path := range environs {
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), "sk-sandbox-secret") {
+			t.Errorf("secret visible in %s", path)
+		}
+	}
+}
+`,
+	})
+	v := sandboxedValidator(t, workDir, DefaultSandboxLimits)
+
+	result := v.RunTests(context.Background())
+	if !result.Success {
+		t.Fatalf("Expected sandboxed tests to pass, got %v\n%s", result.Error, result.Output)
+	}
+	if result.Tests == nil || result.Tests.Count(TestPass) != 4 {
+		t.Errorf("Expected 4 passing tests, got %+v", result.Tests)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "leak.txt")); err == nil {
+		t.Error("Sandboxed tests should not write outside the scratch directory")
+	}
+
+	// The coverage profile is written and read back inside the scratch copy
+	report, err := v.GenerateCoverageReport(context.Background())
+	if err != nil {
+		t.Fatalf("Expected a coverage report, got %v", err)
+	}
+	if !strings.Contains(report, "Add") {
+		t.Errorf("Unexpected coverage report:\n%s", report)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "coverage.out")); err == nil {
+		t.Error("Sandboxed coverage should not write to the work directory")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package validator
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// errSandboxUnsupported is returned on platforms without namespaces
+var errSandboxUnsupported = errors.New("sandboxing is only supported on Linux")
+
+// SandboxMain returns immediately since there is no sandbox helper on this platform
+func SandboxMain() {}
+
+// checkSandbox reports that the sandbox is unavailable on this platform
+func checkSandbox() error {
+	return errSandboxUnsupported
+}
+
+// sandboxCommand is never reached since EnableSandbox fails on this platform
+func sandboxCommand(ctx context.Context, dir string, env []string, spec sandboxSpec) (*exec.Cmd, error) {
+	return nil, errSandboxUnsupported
+}
@@ -0,0 +1,435 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerPackage is the main package the smoke test builds and starts
+const ServerPackage = "./cmd/server"
+
+// Endpoint is an HTTP endpoint of the generated API
+type Endpoint struct {
+	Method string
+	Path   string // Item paths hold an ":id" or "{id}" parameter
+}
+
+// endpointPattern matches list items such as "- GET    /todos/:id - Get a todo"
+var endpointPattern = regexp.MustCompile(`^\s*[-*]\s+(GET|POST|PUT|PATCH|DELETE)\s+(/\S*)`)
+
+// ParseEndpoints returns the endpoints listed in a README, in order
+func ParseEndpoints(readme string) []Endpoint {
+	var endpoints []Endpoint
+	for _, line := range strings.Split(readme, "\n") {
+		if match := endpointPattern.FindStringSubmatch(line); match != nil {
+			endpoints = append(endpoints, Endpoint{Method: match[1], Path: match[2]})
+		}
+	}
+	return endpoints
+}
+
+// isItemPath reports whether an endpoint path addresses a single resource
+func isItemPath(p string) bool {
+	return strings.Contains(p, ":") || strings.Contains(p, "{")
+}
+
+// SmokeTest builds the generated server, starts it on a random local port and
+// runs a CRUD scenario against the endpoints listed in the generated README,
+// checking status codes and the shape of JSON responses. The server gets
+// PORT and DB_PATH in its environment and is stopped with an interrupt
+func (v *Validator) SmokeTest(ctx context.Context) ValidationResult {
+	ctx, cancel := withTimeout(ctx, v.timeout*4) // Building cgo dependencies such as go-sqlite3 is slow
+	defer cancel()
+
+	result := ValidationResult{Tool: "smoke test"}
+
+	readme, _ := os.ReadFile(filepath.Join(v.workDir, "README.md"))
+	endpoints := ParseEndpoints(string(readme))
+	if _, err := os.Stat(filepath.Join(v.workDir, ServerPackage)); err != nil || len(endpoints) == 0 {
+		result.Success = true
+		result.Output = "No server or API endpoints to test, skipped"
+		return result
+	}
+
+	dir, cleanup, err := v.prepare(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer cleanup()
+
+	// The server binary and its database live in a temporary directory
+	tmpDir, err := os.MkdirTemp(v.tempDir(dir), "smoke-*")
+	if err != nil {
+		result.Error = fmt.Errorf("failed to create temp directory: %w", err)
+		return result
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Build the server
+	binary := filepath.Join(tmpDir, "server")
+	build, err := v.command(ctx, dir, "go", "build", "-o", binary, ServerPackage)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if out, err := build.CombinedOutput(); err != nil {
+		result.Output = string(out)
+		result.Error = fmt.Errorf("failed to build server: %w", err)
+		result.Diagnostics = ParseDiagnostics("go build", result.Output, dir)
+		return result
+	}
+
+	port, err := freePort()
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	var report smokeReport
+	if v.sandbox == nil {
+		report = runSmokeServer(ctx, binary, tmpDir, port, endpoints)
+	} else if report, err = v.sandboxSmoke(ctx, dir, binary, tmpDir, port, endpoints); err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Success = report.Error == ""
+	result.Output = strings.Join(report.Log, "\n")
+	if !result.Success {
+		result.Error = errors.New(report.Error)
+		if report.ServerOutput != "" {
+			result.Output += "\nServer output:\n" + report.ServerOutput
+		}
+	}
+	return result
+}
+
+// sandboxSmoke runs the smoke test inside the sandbox of the work copy dir, so
+// the server and the requests made to it share a network namespace cut off
+// from the host's. The server runs in serverDir
+func (v *Validator) sandboxSmoke(ctx context.Context, dir, binary, serverDir string, port int, endpoints []Endpoint) (smokeReport, error) {
+	timeout := v.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	var report smokeReport
+	cmd, err := v.sandboxed(ctx, dir, serverDir, sandboxSpec{
+		Args:  []string{binary},
+		Smoke: &smokeSpec{Endpoints: endpoints, Dir: serverDir, Port: port, Timeout: timeout},
+	})
+	if err != nil {
+		return report, err
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return report, fmt.Errorf("sandboxed smoke test failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return report, fmt.Errorf("failed to read smoke test report: %w", err)
+	}
+	return report, nil
+}
+
+// runSmokeServer starts the server binary in dir with PORT and DB_PATH set,
+// runs the scenario against it and shuts it down
+func runSmokeServer(ctx context.Context, binary, dir string, port int, endpoints []Endpoint) smokeReport {
+	// stdout and stderr share one buffer
+	var serverOutput bytes.Buffer
+	server := exec.Command(binary)
+	server.Dir = dir
+	server.Env = append(os.Environ(), "PORT="+strconv.Itoa(port), "DB_PATH="+filepath.Join(dir, "smoke.db"))
+	server.Stdout = &serverOutput
+	server.Stderr = &serverOutput
+	if err := server.Start(); err != nil {
+		return smokeReport{Error: fmt.Sprintf("failed to start server: %v", err)}
+	}
+	var waitErr error
+	exited := make(chan struct{})
+	go func() {
+		waitErr = server.Wait()
+		close(exited)
+	}()
+
+	// Without keep-alive the server has no idle connections to wait for on shutdown
+	s := &smokeSession{
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{DisableKeepAlives: true},
+		},
+		baseURL: fmt.Sprintf("http://127.0.0.1:%d", port),
+	}
+	err := s.waitReady(ctx, endpoints[0].Path, exited)
+	if err == nil {
+		err = s.run(ctx, endpoints)
+	}
+
+	// Shut the server down, giving it a moment to stop cleanly
+	s.client.CloseIdleConnections()
+	select {
+	case <-exited:
+		if err == nil {
+			err = fmt.Errorf("server exited during the test: %v", waitErr)
+		}
+	default:
+		if stopErr := stopServer(server, exited, &waitErr); err == nil {
+			err = stopErr
+		}
+	}
+
+	report := smokeReport{Log: s.log}
+	if err != nil {
+		report.Error = err.Error()
+		report.ServerOutput = serverOutput.String()
+	}
+	return report
+}
+
+// freePort returns a local TCP port that is free at the time of the call
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// shutdownGrace is how long the server has to exit after an interrupt; longer
+// than the 5s http.Server.Shutdown may wait on connections that sent nothing
+const shutdownGrace = 10 * time.Second
+
+// stopServer interrupts the server and kills it if it doesn't exit in time
+// exited is closed once the server's Wait has returned waitErr
+func stopServer(server *exec.Cmd, exited <-chan struct{}, waitErr *error) error {
+	if err := server.Process.Signal(os.Interrupt); err != nil {
+		// Interrupts aren't supported everywhere (e.g. Windows)
+		server.Process.Kill()
+		<-exited
+		return nil
+	}
+
+	select {
+	case <-exited:
+		if *waitErr != nil {
+			return fmt.Errorf("server did not shut down cleanly: %w", *waitErr)
+		}
+		return nil
+	case <-time.After(shutdownGrace):
+		server.Process.Kill()
+		<-exited
+		return fmt.Errorf("server did not shut down within %s of an interrupt", shutdownGrace)
+	}
+}
+
+// smokeSession holds the state of one smoke test scenario
+type smokeSession struct {
+	client  *http.Client
+	baseURL string
+	log     []string // One line per request made
+}
+
+// waitReady polls the server until it answers HTTP requests
+func (s *smokeSession) waitReady(ctx context.Context, path string, exited <-chan struct{}) error {
+	if isItemPath(path) {
+		path = "/"
+	}
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := s.client.Do(req); err == nil {
+			resp.Body.Close()
+			return nil
+		}
+
+		select {
+		case <-exited:
+			return fmt.Errorf("server exited before it was ready")
+		case <-ctx.Done():
+			return fmt.Errorf("server not ready: %w", ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// run executes the CRUD scenario against the listed endpoints, skipping the
+// steps whose endpoint isn't listed, and stops at the first failed step
+func (s *smokeSession) run(ctx context.Context, endpoints []Endpoint) error {
+	var collection, item string
+	listed := make(map[string]bool)
+	for _, e := range endpoints {
+		if isItemPath(e.Path) {
+			item = e.Path
+		} else if collection == "" {
+			collection = e.Path
+		}
+		listed[e.Method+" "+e.Path] = true
+	}
+
+	todo := map[string]any{"title": "Smoke test todo", "description": "Created by the smoke test", "done": false}
+
+	if listed["GET "+collection] {
+		if _, err := s.expectList(ctx, collection); err != nil {
+			return err
+		}
+	}
+	if !listed["POST "+collection] {
+		return nil
+	}
+
+	created, err := s.expectObject(ctx, http.MethodPost, collection, todo, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	id, ok := created["id"].(json.Number)
+	if !ok {
+		return fmt.Errorf("POST %s: response has no numeric \"id\": %v", collection, created)
+	}
+	itemPath := itemURL(item, id.String())
+
+	if listed["GET "+item] {
+		got, err := s.expectObject(ctx, http.MethodGet, itemPath, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		if got["title"] != todo["title"] {
+			return fmt.Errorf("GET %s: expected title %q, got %v", itemPath, todo["title"], got["title"])
+		}
+	}
+
+	if listed["PUT "+item] {
+		todo["id"] = id
+		todo["title"] = "Smoke test todo (updated)"
+		todo["done"] = true
+		got, err := s.expectObject(ctx, http.MethodPut, itemPath, todo, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		if got["title"] != todo["title"] || got["done"] != true {
+			return fmt.Errorf("PUT %s: update not applied, got %v", itemPath, got)
+		}
+	}
+
+	if listed["GET "+collection] {
+		list, err := s.expectList(ctx, collection)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, entry := range list {
+			if obj, ok := entry.(map[string]any); ok && obj["id"] == id {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("GET %s: created todo %s not listed", collection, id)
+		}
+	}
+
+	if listed["DELETE "+item] {
+		if _, err := s.do(ctx, http.MethodDelete, itemPath, nil, http.StatusNoContent); err != nil {
+			return err
+		}
+		if listed["GET "+item] {
+			if _, err := s.do(ctx, http.MethodGet, itemPath, nil, http.StatusNotFound); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// paramPattern matches a path parameter such as ":id" or "{id}"
+var paramPattern = regexp.MustCompile(`:\w+|\{[^}]*\}`)
+
+// itemURL fills in the ID parameter of an item path
+func itemURL(path, id string) string {
+	return paramPattern.ReplaceAllLiteralString(path, id)
+}
+
+// expectList makes a GET request expecting 200 and a JSON array
+func (s *smokeSession) expectList(ctx context.Context, path string) ([]any, error) {
+	body, err := s.do(ctx, http.MethodGet, path, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var list []any
+	if err := decodeJSON(body, &list); err != nil {
+		return nil, fmt.Errorf("GET %s: expected a JSON array: %w", path, err)
+	}
+	return list, nil
+}
+
+// expectObject makes a request expecting the given status and a JSON object
+func (s *smokeSession) expectObject(ctx context.Context, method, path string, payload any, status int) (map[string]any, error) {
+	body, err := s.do(ctx, method, path, payload, status)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]any
+	if err := decodeJSON(body, &obj); err != nil {
+		return nil, fmt.Errorf("%s %s: expected a JSON object: %w", method, path, err)
+	}
+	return obj, nil
+}
+
+// do makes a request and checks its status code, returning the body
+func (s *smokeSession) do(ctx context.Context, method, path string, payload any, status int) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.log = append(s.log, fmt.Sprintf("FAIL %s %s: %v", method, path, err))
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != status {
+		s.log = append(s.log, fmt.Sprintf("FAIL %s %s -> %d (expected %d)", method, path, resp.StatusCode, status))
+		return nil, fmt.Errorf("%s %s: expected status %d, got %d: %s", method, path, status, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	s.log = append(s.log, fmt.Sprintf("PASS %s %s -> %d", method, path, resp.StatusCode))
+	return body, nil
+}
+
+// decodeJSON decodes a response body, keeping numbers exact
+func decodeJSON(body []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
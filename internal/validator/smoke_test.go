@@ -0,0 +1,158 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// smokeReadme lists the endpoints of the built-in Todo API README
+const smokeReadme = `# Generated Todo API
+
+## API Endpoints
+
+- GET    /todos     - List all todos
+- GET    /todos/:id - Get a specific todo
+- POST   /todos     - Create a new todo
+- PUT    /todos/:id - Update a todo
+- DELETE /todos/:id - Delete a todo
+`
+
+// smokeServer is a minimal in-memory Todo API; CREATED is replaced by the
+// status returned for new todos
+const smokeServer = `package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type todo struct {
+	ID    int64  ` + "`json:\"id\"`" + `
+	Title string ` + "`json:\"title\"`" + `
+	Done  bool   ` + "`json:\"done\"`" + `
+}
+
+func main() {
+	var mu sync.Mutex
+	todos := map[int64]todo{}
+	var next int64
+
+	write := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/todos", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			var t todo
+			json.NewDecoder(r.Body).Decode(&t)
+			next++
+			t.ID = next
+			todos[t.ID] = t
+			write(w, CREATED, t)
+			return
+		}
+		list := []todo{}
+		for _, t := range todos {
+			list = append(list, t)
+		}
+		write(w, http.StatusOK, list)
+	})
+	mux.HandleFunc("/todos/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/todos/"), 10, 64)
+		t, ok := todos[id]
+		if !ok {
+			write(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			json.NewDecoder(r.Body).Decode(&t)
+			t.ID = id
+			todos[id] = t
+			write(w, http.StatusOK, t)
+		case http.MethodDelete:
+			delete(todos, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			write(w, http.StatusOK, t)
+		}
+	})
+
+	srv := &http.Server{Addr: ":" + os.Getenv("PORT"), Handler: mux}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go srv.ListenAndServe()
+	<-ctx.Done()
+	srv.Shutdown(context.Background())
+}
+`
+
+// TestParseEndpoints verifies endpoints are read from README list items
+func TestParseEndpoints(t *testing.T) {
+	endpoints := ParseEndpoints(smokeReadme + "\nSee GET /todos for details.\n")
+	if len(endpoints) != 5 {
+		t.Fatalf("Expected 5 endpoints, got %+v", endpoints)
+	}
+	if endpoints[1] != (Endpoint{Method: "GET", Path: "/todos/:id"}) {
+		t.Errorf("Unexpected endpoint: %+v", endpoints[1])
+	}
+	if got := itemURL("/todos/{id}", "7"); got != "/todos/7" {
+		t.Errorf("Expected /todos/7, got %s", got)
+	}
+}
+
+// TestSmokeTest verifies the CRUD scenario passes against a working server
+// and reports the failing step of a broken one
+func TestSmokeTest(t *testing.T) {
+	if err := NewValidator(t.TempDir()).CheckGoInstallation(); err != nil {
+		t.Skip("go not available on test system")
+	}
+
+	workDir := writeModule(t, map[string]string{
+		"go.mod":             "module example.com/todo\n\ngo 1.21\n",
+		"README.md":          smokeReadme,
+		"cmd/server/main.go": strings.Replace(smokeServer, "CREATED", "http.StatusCreated", 1),
+	})
+	result := NewValidator(workDir).SmokeTest(context.Background())
+	if !result.Success {
+		t.Fatalf("Expected the smoke test to pass, got %v\n%s", result.Error, result.Output)
+	}
+	for _, want := range []string{"PASS POST /todos -> 201", "PASS PUT /todos/1 -> 200", "PASS DELETE /todos/1 -> 204", "PASS GET /todos/1 -> 404"} {
+		if !strings.Contains(result.Output, want) {
+			t.Errorf("Output missing %q:\n%s", want, result.Output)
+		}
+	}
+
+	workDir = writeModule(t, map[string]string{
+		"go.mod":             "module example.com/todo\n\ngo 1.21\n",
+		"README.md":          smokeReadme,
+		"cmd/server/main.go": strings.Replace(smokeServer, "CREATED", "http.StatusOK", 1),
+	})
+	result = NewValidator(workDir).SmokeTest(context.Background())
+	if result.Success || result.Error == nil || !strings.Contains(result.Error.Error(), "expected status 201, got 200") {
+		t.Errorf("Expected the create step to fail, got %v\n%s", result.Error, result.Output)
+	}
+}
+
+// TestSmokeTestSkipped verifies projects without a server are skipped
+func TestSmokeTestSkipped(t *testing.T) {
+	workDir := writeModule(t, map[string]string{"go.mod": "module example.com/lib\n"})
+	result := NewValidator(workDir).SmokeTest(context.Background())
+	if !result.Success || !strings.Contains(result.Output, "skipped") {
+		t.Errorf("Expected a skipped smoke test, got %+v", result)
+	}
+}
@@ -0,0 +1,222 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Built-in validation stages
+const (
+	StageFormat   = "format"   // gofmt -l
+	StageVet      = "vet"      // go vet
+	StageBuild    = "build"    // go build
+	StageTest     = "test"     // go test -json, see RunTests
+	StageCoverage = "coverage" // go test -coverprofile, see GenerateCoverageReport
+	StageSmoke    = "smoke"    // Boot the generated API, see SmokeTest
+)
+
+// builtinStages lists the built-in stage names in their usual order
+var builtinStages = []string{StageFormat, StageVet, StageBuild, StageTest, StageCoverage, StageSmoke}
+
+// Stage is one step of the validation pipeline
+// Built-in stages are selected by name; any other name is a custom stage
+// running Command in the work directory (inside the sandbox if enabled)
+type Stage struct {
+	Name     string   `json:"name"`
+	Command  []string `json:"command,omitempty"`  // Custom stages only: program and arguments
+	Timeout  string   `json:"timeout,omitempty"`  // e.g. "90s"; empty uses the stage's default
+	Required bool     `json:"required,omitempty"` // A failure fails the whole run
+}
+
+// DefaultStages is the validation pipeline used when none is configured
+var DefaultStages = []Stage{
+	{Name: StageFormat},
+	{Name: StageVet},
+	{Name: StageBuild, Required: true},
+	{Name: StageTest},
+	{Name: StageSmoke},
+}
+
+// Validate checks a stage's definition
+func (s Stage) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("stage has no name")
+	}
+	builtin := isBuiltinStage(s.Name)
+	if builtin && len(s.Command) > 0 {
+		return fmt.Errorf("stage %s is built in and takes no command", s.Name)
+	}
+	if !builtin && len(s.Command) == 0 {
+		return fmt.Errorf("stage %s is not built in (%s) and has no command", s.Name, strings.Join(builtinStages, ", "))
+	}
+	if s.Timeout != "" {
+		d, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return fmt.Errorf("stage %s has invalid timeout: %w", s.Name, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("stage %s has non-positive timeout %s", s.Name, s.Timeout)
+		}
+	}
+	return nil
+}
+
+// ValidateStages checks every stage and that names are unique
+func ValidateStages(stages []Stage) error {
+	seen := make(map[string]bool, len(stages))
+	for _, s := range stages {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("duplicate stage: %s", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	return nil
+}
+
+// SelectStages returns the named stages in the given order, taking each from
+// configured if it is there and otherwise using the built-in stage's defaults
+// No names selects every configured stage
+func SelectStages(configured []Stage, names []string) ([]Stage, error) {
+	if len(names) == 0 {
+		return configured, nil
+	}
+
+	byName := make(map[string]Stage, len(configured))
+	for _, s := range configured {
+		byName[s.Name] = s
+	}
+
+	selected := make([]Stage, 0, len(names))
+	for _, name := range names {
+		s, ok := byName[name]
+		if !ok {
+			for _, d := range DefaultStages {
+				if d.Name == name {
+					s, ok = d, true
+				}
+			}
+		}
+		if !ok && isBuiltinStage(name) {
+			s, ok = Stage{Name: name}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown stage %s: custom stages must be defined in the pipeline", name)
+		}
+		selected = append(selected, s)
+	}
+	return selected, ValidateStages(selected)
+}
+
+// isBuiltinStage reports whether name is a built-in stage
+func isBuiltinStage(name string) bool {
+	for _, b := range builtinStages {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stageKey marks a context whose deadline was set by a stage's timeout
+type stageKey struct{}
+
+// withTimeout applies a check's default timeout, unless the check runs as a
+// stage with its own timeout
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if ctx.Value(stageKey{}) != nil {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// RunStages runs validation stages in order and returns one result per stage
+// Every stage runs even if an earlier one failed; use FailedGates to check
+// the required ones
+func (v *Validator) RunStages(ctx context.Context, stages []Stage) []ValidationResult {
+	results := make([]ValidationResult, 0, len(stages))
+	for _, stage := range stages {
+		start := time.Now()
+		result := v.runStage(ctx, stage)
+		result.Stage = stage.Name
+		result.Required = stage.Required
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+// runStage runs a single stage within its timeout
+func (v *Validator) runStage(ctx context.Context, stage Stage) ValidationResult {
+	if stage.Timeout != "" {
+		timeout, err := time.ParseDuration(stage.Timeout)
+		if err != nil {
+			return ValidationResult{Tool: stage.Name, Error: fmt.Errorf("invalid timeout: %w", err)}
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithValue(ctx, stageKey{}, true), timeout)
+		defer cancel()
+	}
+
+	switch stage.Name {
+	case StageFormat:
+		return v.checkFormat(ctx)
+	case StageVet:
+		return v.runVet(ctx)
+	case StageBuild:
+		return v.tryBuild(ctx)
+	case StageTest:
+		return v.RunTests(ctx)
+	case StageCoverage:
+		report, err := v.GenerateCoverageReport(ctx)
+		return ValidationResult{Tool: "go test -cover", Success: err == nil, Output: report, Error: err}
+	case StageSmoke:
+		return v.SmokeTest(ctx)
+	default:
+		return v.runCommand(ctx, stage)
+	}
+}
+
+// runCommand runs a custom stage's command and parses any diagnostics in its output
+func (v *Validator) runCommand(ctx context.Context, stage Stage) ValidationResult {
+	ctx, cancel := withTimeout(ctx, v.timeout)
+	defer cancel()
+
+	result := ValidationResult{Tool: stage.Name}
+	dir, cleanup, err := v.prepare(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer cleanup()
+
+	cmd, err := v.command(ctx, dir, stage.Command[0], stage.Command[1:]...)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	out, err := cmd.CombinedOutput()
+	result.Success = err == nil
+	result.Output = string(out)
+	if err != nil {
+		result.Error = fmt.Errorf("%s failed: %w", strings.Join(stage.Command, " "), err)
+	}
+	result.Diagnostics = ParseDiagnostics(result.Tool, result.Output, dir)
+	return result
+}
+
+// FailedGates returns the required stages that failed
+func FailedGates(results []ValidationResult) []ValidationResult {
+	var failed []ValidationResult
+	for _, r := range results {
+		if r.Required && !r.Success {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
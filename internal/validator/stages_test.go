@@ -0,0 +1,133 @@
+package validator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestValidateStages verifies stage definitions are checked
+func TestValidateStages(t *testing.T) {
+	tests := []struct {
+		name          string
+		stages        []Stage
+		errorContains string
+	}{
+		{name: "defaults", stages: DefaultStages},
+		{name: "custom command", stages: []Stage{{Name: "lint", Command: []string{"staticcheck", "./..."}, Timeout: "2m"}}},
+		{name: "no name", stages: []Stage{{Command: []string{"true"}}}, errorContains: "no name"},
+		{name: "builtin with command", stages: []Stage{{Name: StageVet, Command: []string{"go", "vet"}}}, errorContains: "takes no command"},
+		{name: "custom without command", stages: []Stage{{Name: "lint"}}, errorContains: "has no command"},
+		{name: "invalid timeout", stages: []Stage{{Name: StageTest, Timeout: "soon"}}, errorContains: "invalid timeout"},
+		{name: "negative timeout", stages: []Stage{{Name: StageTest, Timeout: "-1s"}}, errorContains: "non-positive timeout"},
+		{name: "duplicate", stages: []Stage{{Name: StageVet}, {Name: StageVet}}, errorContains: "duplicate stage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStages(tt.stages)
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("Expected error containing '%s', got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
+
+// TestSelectStages verifies stages are picked from the configuration, then the defaults
+func TestSelectStages(t *testing.T) {
+	configured := []Stage{
+		{Name: StageTest, Timeout: "5m", Required: true},
+		{Name: "lint", Command: []string{"staticcheck", "./..."}},
+	}
+
+	all, err := SelectStages(configured, nil)
+	if err != nil || len(all) != len(configured) {
+		t.Fatalf("Expected every configured stage, got %v (%v)", all, err)
+	}
+
+	selected, err := SelectStages(configured, []string{"lint", StageBuild, StageTest, StageCoverage})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []Stage{
+		configured[1],
+		{Name: StageBuild, Required: true}, // From DefaultStages
+		configured[0],
+		{Name: StageCoverage},
+	}
+	if len(selected) != len(want) {
+		t.Fatalf("Expected %d stages, got %d", len(want), len(selected))
+	}
+	for i := range want {
+		if selected[i].Name != want[i].Name || selected[i].Timeout != want[i].Timeout || selected[i].Required != want[i].Required {
+			t.Errorf("Stage %d: expected %+v, got %+v", i, want[i], selected[i])
+		}
+	}
+
+	if _, err := SelectStages(configured, []string{"typecheck"}); err == nil || !strings.Contains(err.Error(), "unknown stage typecheck") {
+		t.Errorf("Expected unknown stage error, got %v", err)
+	}
+	if _, err := SelectStages(configured, []string{StageVet, StageVet}); err == nil {
+		t.Error("Expected duplicate stage error")
+	}
+}
+
+// TestRunStages verifies custom commands, per-stage timeouts and required gates
+func TestRunStages(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := NewValidator(workDir)
+
+	start := time.Now()
+	results := v.RunStages(context.Background(), []Stage{
+		{Name: "list", Command: []string{"sh", "-c", "ls"}},
+		{Name: "lint", Command: []string{"sh", "-c", "echo main.go:3:1: unused thing; exit 1"}},
+		{Name: "slow", Command: []string{"sleep", "10"}, Timeout: "200ms", Required: true},
+	})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stage timeout not enforced, took %v", elapsed)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	list, lint, slow := results[0], results[1], results[2]
+
+	if !list.Success || list.Stage != "list" || !strings.Contains(list.Output, "main.go") {
+		t.Errorf("Expected list to pass in the work directory, got %+v", list)
+	}
+	if list.Duration <= 0 {
+		t.Error("Expected a stage duration")
+	}
+
+	if lint.Success || lint.Required {
+		t.Errorf("Expected lint to fail as an optional stage, got %+v", lint)
+	}
+	if len(lint.Diagnostics) != 1 || lint.Diagnostics[0].File != "main.go" || lint.Diagnostics[0].Line != 3 {
+		t.Errorf("Expected a diagnostic at main.go:3, got %+v", lint.Diagnostics)
+	}
+
+	if slow.Success || !slow.Required {
+		t.Errorf("Expected slow to time out as a required stage, got %+v", slow)
+	}
+
+	failed := FailedGates(results)
+	if len(failed) != 1 || failed[0].Stage != "slow" {
+		t.Errorf("Expected only slow to fail its gate, got %+v", failed)
+	}
+}
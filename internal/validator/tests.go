@@ -0,0 +1,163 @@
+package validator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcomes of a test or package reported by go test -json
+const (
+	TestPass = "pass"
+	TestFail = "fail"
+	TestSkip = "skip"
+)
+
+// TestResult is the outcome of a single test, or of a whole package
+type TestResult struct {
+	Package string
+	Test    string // Empty for package results; subtests are "TestX/case"
+	Status  string // TestPass, TestFail or TestSkip
+	Elapsed time.Duration
+	Output  string // Output of failed tests and packages; empty otherwise
+}
+
+// TestReport holds the per-package and per-test results of a go test run
+type TestReport struct {
+	Packages []TestResult
+	Tests    []TestResult
+}
+
+// Count returns the number of tests with the given status
+func (r *TestReport) Count(status string) int {
+	n := 0
+	for _, t := range r.Tests {
+		if t.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the failed tests and, for packages that failed without a
+// failing test (build errors, panics in init, timeouts), the packages
+func (r *TestReport) Failed() []TestResult {
+	failedTests := make(map[string]bool)
+	var failed []TestResult
+	for _, t := range r.Tests {
+		if t.Status == TestFail {
+			failedTests[t.Package] = true
+			failed = append(failed, t)
+		}
+	}
+	for _, p := range r.Packages {
+		if p.Status == TestFail && !failedTests[p.Package] {
+			failed = append(failed, p)
+		}
+	}
+	return failed
+}
+
+// testEvent is a line of go test -json output, see "go doc test2json"
+type testEvent struct {
+	Action      string
+	Package     string
+	Test        string
+	Elapsed     float64
+	Output      string
+	ImportPath  string // Set on build-output events instead of Package
+	FailedBuild string // Import path whose build failure failed the package
+}
+
+// ParseTestEvents parses the event stream written by go test -json
+// Lines that aren't JSON events, such as build errors of older Go versions,
+// are ignored. Tests still running when the stream ends (after a timeout or a
+// crash of the test binary) are reported as failed
+func ParseTestEvents(r io.Reader) (*TestReport, error) {
+	type key struct{ pkg, test string }
+
+	output := make(map[key]*strings.Builder)
+	buildOutput := make(map[string]*strings.Builder)
+	results := make(map[key]*TestResult)
+	var order []key
+
+	appendTo := func(m map[key]*strings.Builder, k key, s string) {
+		if m[k] == nil {
+			m[k] = &strings.Builder{}
+		}
+		m[k].WriteString(s)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var ev testEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue
+		}
+
+		k := key{ev.Package, ev.Test}
+		switch ev.Action {
+		case "build-output":
+			if buildOutput[ev.ImportPath] == nil {
+				buildOutput[ev.ImportPath] = &strings.Builder{}
+			}
+			buildOutput[ev.ImportPath].WriteString(ev.Output)
+		case "run":
+			if results[k] == nil {
+				results[k] = &TestResult{Package: ev.Package, Test: ev.Test}
+				order = append(order, k)
+			}
+		case "output":
+			appendTo(output, k, ev.Output)
+		case TestPass, TestFail, TestSkip:
+			res := results[k]
+			if res == nil {
+				res = &TestResult{Package: ev.Package, Test: ev.Test}
+				results[k] = res
+				order = append(order, k)
+			}
+			res.Status = ev.Action
+			res.Elapsed = time.Duration(ev.Elapsed * float64(time.Second))
+			if ev.Action == TestFail {
+				var out strings.Builder
+				if b := buildOutput[ev.FailedBuild]; ev.FailedBuild != "" && b != nil {
+					out.WriteString(b.String())
+				}
+				if b := output[k]; b != nil {
+					out.WriteString(b.String())
+				}
+				res.Output = strings.TrimSpace(out.String())
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read test events: %w", err)
+	}
+
+	report := &TestReport{}
+	for _, k := range order {
+		res := results[k]
+		if res.Status == "" {
+			res.Status = TestFail
+			if b := output[k]; b != nil {
+				res.Output = strings.TrimSpace(b.String())
+			}
+		}
+		if res.Test == "" {
+			report.Packages = append(report.Packages, *res)
+		} else {
+			report.Tests = append(report.Tests, *res)
+		}
+	}
+	sort.SliceStable(report.Packages, func(i, j int) bool { return report.Packages[i].Package < report.Packages[j].Package })
+	return report, nil
+}
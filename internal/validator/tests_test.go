@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseTestEvents verifies go test -json output is split into package and test results
+func TestParseTestEvents(t *testing.T) {
+	stream := `{"Action":"start","Package":"todo-api/internal/models"}
+{"Action":"run","Package":"todo-api/internal/models","Test":"TestValidate"}
+{"Action":"output","Package":"todo-api/internal/models","Test":"TestValidate","Output":"=== RUN   TestValidate\n"}
+{"Action":"output","Package":"todo-api/internal/models","Test":"TestValidate","Output":"    todo_test.go:12: title is required\n"}
+{"Action":"fail","Package":"todo-api/internal/models","Test":"TestValidate","Elapsed":0.25}
+{"Action":"run","Package":"todo-api/internal/models","Test":"TestCreate"}
+{"Action":"pass","Package":"todo-api/internal/models","Test":"TestCreate","Elapsed":0.01}
+{"Action":"output","Package":"todo-api/internal/models","Output":"FAIL\n"}
+{"Action":"fail","Package":"todo-api/internal/models","Elapsed":0.3}
+{"ImportPath":"todo-api/internal/handlers [todo-api/internal/handlers.test]","Action":"build-output","Output":"internal/handlers/todo_test.go:8:2: undefined: newServer\n"}
+{"ImportPath":"todo-api/internal/handlers [todo-api/internal/handlers.test]","Action":"build-fail"}
+{"Action":"start","Package":"todo-api/internal/handlers"}
+{"Action":"output","Package":"todo-api/internal/handlers","Output":"FAIL\ttodo-api/internal/handlers [build failed]\n"}
+{"Action":"fail","Package":"todo-api/internal/handlers","Elapsed":0,"FailedBuild":"todo-api/internal/handlers [todo-api/internal/handlers.test]"}
+{"Action":"output","Package":"todo-api","Output":"?   \ttodo-api\t[no test files]\n"}
+{"Action":"skip","Package":"todo-api","Elapsed":0}
+`
+	report, err := ParseTestEvents(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Tests) != 2 {
+		t.Fatalf("Expected 2 tests, got %+v", report.Tests)
+	}
+	failed := report.Tests[0]
+	if failed.Test != "TestValidate" || failed.Status != TestFail || failed.Elapsed != 250*time.Millisecond {
+		t.Errorf("Unexpected failed test: %+v", failed)
+	}
+	if !strings.Contains(failed.Output, "title is required") {
+		t.Errorf("Expected the failure output, got %q", failed.Output)
+	}
+	if passed := report.Tests[1]; passed.Status != TestPass || passed.Output != "" {
+		t.Errorf("Passing tests should keep no output, got %+v", passed)
+	}
+
+	statuses := make(map[string]string)
+	for _, p := range report.Packages {
+		statuses[p.Package] = p.Status
+	}
+	want := map[string]string{"todo-api": TestSkip, "todo-api/internal/models": TestFail, "todo-api/internal/handlers": TestFail}
+	for pkg, status := range want {
+		if statuses[pkg] != status {
+			t.Errorf("Expected package %s to %s, got %q", pkg, status, statuses[pkg])
+		}
+	}
+
+	// The build failure is reported with its compiler output
+	failures := report.Failed()
+	if len(failures) != 2 || failures[1].Package != "todo-api/internal/handlers" ||
+		!strings.Contains(failures[1].Output, "undefined: newServer") {
+		t.Errorf("Unexpected failures: %+v", failures)
+	}
+}
+
+// TestParseTestEventsUnfinished verifies tests cut off by a crash or timeout count as failed
+func TestParseTestEventsUnfinished(t *testing.T) {
+	stream := `go: downloading example.com/dep v1.0.0
+{"Action":"run","Package":"todo-api","Test":"TestHangs"}
+{"Action":"output","Package":"todo-api","Test":"TestHangs","Output":"=== RUN   TestHangs\n"}
+`
+	report, err := ParseTestEvents(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Tests) != 1 || report.Tests[0].Status != TestFail || report.Count(TestFail) != 1 {
+		t.Errorf("Expected the unfinished test to fail, got %+v", report.Tests)
+	}
+}
@@ -3,6 +3,7 @@ package validator
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 type Validator struct {
 	workDir string
 	timeout time.Duration
+	sandbox *sandboxConfig // Runs generated code in a sandbox when set
 }
 
 // NewValidator creates a new code validator instance
@@ -27,10 +29,17 @@ func NewValidator(workDir string) *Validator {
 
 // ValidationResult contains the outcome of a validation check
 type ValidationResult struct {
-	Tool    string // The tool that was run (fmt, vet, build)
-	Success bool
-	Output  string
-	Error   error
+	Tool        string // The tool that was run (fmt, vet, build)
+	Success     bool
+	Output      string
+	Error       error
+	Diagnostics []Diagnostic // Problems parsed from Output, tied to source locations
+	Tests       *TestReport  // Per-package and per-test results; go test only
+
+	// Set by RunStages
+	Stage    string        // Name of the stage that produced the result
+	Required bool          // Whether the stage is a required gate
+	Duration time.Duration // How long the stage took
 }
 
 // ValidateAll runs all validation checks on the generated code
@@ -53,7 +62,7 @@ func (v *Validator) ValidateAll(ctx context.Context) []ValidationResult {
 // checkFormat verifies if the code is properly formatted
 // Uses gofmt -l to list files that need formatting without modifying them
 func (v *Validator) checkFormat(ctx context.Context) ValidationResult {
-	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	ctx, cancel := withTimeout(ctx, v.timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "gofmt", "-l", v.workDir)
@@ -74,8 +83,12 @@ func (v *Validator) checkFormat(ctx context.Context) ValidationResult {
 	if strings.TrimSpace(stdout.String()) != "" {
 		result.Success = false
 		result.Output = fmt.Sprintf("Files need formatting:\n%s", stdout.String())
+		result.Diagnostics = parseUnformatted(stdout.String(), v.workDir)
 	}
 
+	// Files gofmt can't parse are reported on stderr
+	result.Diagnostics = append(result.Diagnostics, ParseDiagnostics(result.Tool, stderr.String(), v.workDir)...)
+
 	// Check for actual errors running gofmt
 	if err != nil {
 		result.Success = false
@@ -87,20 +100,31 @@ func (v *Validator) checkFormat(ctx context.Context) ValidationResult {
 
 // runVet performs static analysis on the generated code
 func (v *Validator) runVet(ctx context.Context) ValidationResult {
-	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	ctx, cancel := withTimeout(ctx, v.timeout)
 	defer cancel()
 
-	// Change to work directory for proper module context
-	cmd := exec.CommandContext(ctx, "go", "vet", "./...")
-	cmd.Dir = v.workDir
+	result := ValidationResult{Tool: "go vet"}
+	dir, cleanup, err := v.prepare(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer cleanup()
+
+	// Run in the work directory for proper module context
+	cmd, err := v.command(ctx, dir, "go", "vet", "./...")
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 
-	result := ValidationResult{
+	result = ValidationResult{
 		Tool:    "go vet",
 		Success: err == nil,
 		Output:  stdout.String(),
@@ -115,6 +139,7 @@ func (v *Validator) runVet(ctx context.Context) ValidationResult {
 	if err != nil {
 		result.Error = fmt.Errorf("go vet found issues: %w", err)
 	}
+	result.Diagnostics = ParseDiagnostics(result.Tool, result.Output, dir)
 
 	return result
 }
@@ -122,7 +147,7 @@ func (v *Validator) runVet(ctx context.Context) ValidationResult {
 // tryBuild attempts to compile the generated code
 // This is the most comprehensive validation as it checks syntax, types, and dependencies
 func (v *Validator) tryBuild(ctx context.Context) ValidationResult {
-	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	ctx, cancel := withTimeout(ctx, v.timeout)
 	defer cancel()
 
 	// First, ensure go.mod dependencies are downloaded
@@ -130,17 +155,28 @@ func (v *Validator) tryBuild(ctx context.Context) ValidationResult {
 	modCmd.Dir = v.workDir
 	modCmd.Run() // Ignore errors, build will catch missing deps
 
+	result := ValidationResult{Tool: "go build"}
+	dir, cleanup, err := v.prepare(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer cleanup()
+
 	// Try to build all packages
-	cmd := exec.CommandContext(ctx, "go", "build", "./...")
-	cmd.Dir = v.workDir
+	cmd, err := v.command(ctx, dir, "go", "build", "./...")
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 
-	result := ValidationResult{
+	result = ValidationResult{
 		Tool:    "go build",
 		Success: err == nil,
 		Output:  stdout.String(),
@@ -154,6 +190,7 @@ func (v *Validator) tryBuild(ctx context.Context) ValidationResult {
 	if err != nil {
 		result.Error = fmt.Errorf("build failed: %w", err)
 	}
+	result.Diagnostics = ParseDiagnostics(result.Tool, result.Output, dir)
 
 	return result
 }
@@ -176,32 +213,65 @@ func (v *Validator) FormatCode(ctx context.Context) error {
 	return nil
 }
 
-// RunTests executes the test suite for the generated code
+// RunTests executes the test suite for the generated code with go test -json
+// The event stream is parsed into per-package and per-test results in Tests,
+// and compile errors of the tests into Diagnostics
 func (v *Validator) RunTests(ctx context.Context) ValidationResult {
-	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	ctx, cancel := withTimeout(ctx, v.timeout*2) // Tests build and run every package
 	defer cancel()
 
-	// Run tests with verbose output and coverage
-	cmd := exec.CommandContext(ctx, "go", "test", "-v", "-cover", "./...")
-	cmd.Dir = v.workDir
+	result := ValidationResult{Tool: "go test"}
+	dir, cleanup, err := v.prepare(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer cleanup()
+
+	cmd, err := v.command(ctx, dir, "go", "test", "-json", "./...")
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 
-	result := ValidationResult{
+	result = ValidationResult{
 		Tool:    "go test",
 		Success: err == nil,
-		Output:  stdout.String(),
+		Output:  stderr.String(),
 		Error:   err,
 	}
 
-	if stderr.String() != "" {
-		result.Output += "\n" + stderr.String()
+	// Without an exit status go test didn't run at all (e.g. not installed)
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		result.Error = fmt.Errorf("failed to run go test: %w", err)
+		return result
 	}
 
+	report, parseErr := ParseTestEvents(bytes.NewReader(stdout.Bytes()))
+	if parseErr != nil {
+		result.Success = false
+		result.Error = parseErr
+		return result
+	}
+	result.Tests = report
+
+	// Build errors are in the output of failed packages, or on stderr
+	var failures strings.Builder
+	failures.WriteString(stderr.String())
+	for _, p := range report.Packages {
+		if p.Status == TestFail {
+			failures.WriteString("\n" + p.Output)
+		}
+	}
+	result.Diagnostics = ParseDiagnostics(result.Tool, failures.String(), dir)
+
 	if err != nil {
 		result.Error = fmt.Errorf("tests failed: %w", err)
 	}
@@ -252,6 +322,7 @@ func (v *Validator) ValidateFile(ctx context.Context, filePath string) Validatio
 	if stderr.String() != "" {
 		result.Output = stderr.String()
 		result.Success = false
+		result.Diagnostics = ParseDiagnostics("gofmt", stderr.String(), v.workDir)
 	}
 
 	return result
@@ -259,13 +330,21 @@ func (v *Validator) ValidateFile(ctx context.Context, filePath string) Validatio
 
 // GenerateCoverageReport creates a test coverage report
 func (v *Validator) GenerateCoverageReport(ctx context.Context) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, v.timeout*2) // Extra time for coverage
+	ctx, cancel := withTimeout(ctx, v.timeout*2) // Extra time for coverage
 	defer cancel()
 
-	// Generate coverage profile
-	coverFile := filepath.Join(v.workDir, "coverage.out")
-	cmd := exec.CommandContext(ctx, "go", "test", "-coverprofile="+coverFile, "./...")
-	cmd.Dir = v.workDir
+	dir, cleanup, err := v.prepare(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	// Generate coverage profile, in the scratch copy when sandboxed
+	coverFile := filepath.Join(dir, "coverage.out")
+	cmd, err := v.command(ctx, dir, "go", "test", "-coverprofile="+coverFile, "./...")
+	if err != nil {
+		return "", err
+	}
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -276,7 +355,7 @@ func (v *Validator) GenerateCoverageReport(ctx context.Context) (string, error)
 
 	// Generate text report
 	reportCmd := exec.CommandContext(ctx, "go", "tool", "cover", "-func="+coverFile)
-	reportCmd.Dir = v.workDir
+	reportCmd.Dir = dir
 
 	var reportOut bytes.Buffer
 	reportCmd.Stdout = &reportOut
@@ -302,9 +381,22 @@ func PrintResults(results []ValidationResult) {
 			allPassed = false
 		}
 
-		fmt.Printf("\n%s - %s\n", result.Tool, status)
-
-		if result.Output != "" {
+		gate := ""
+		if result.Required {
+			gate = " (required)"
+		}
+		fmt.Printf("\n%s - %s%s\n", result.Tool, status, gate)
+
+		if len(result.Diagnostics) > 0 {
+			fmt.Println("Diagnostics:")
+			for _, d := range result.Diagnostics {
+				fmt.Printf("  %s: %s", d.Severity, d)
+				if d.TaskID != "" {
+					fmt.Printf(" (task %s)", d.TaskID)
+				}
+				fmt.Println()
+			}
+		} else if result.Output != "" {
 			fmt.Println("Output:")
 			// Indent output for readability
 			lines := strings.Split(strings.TrimSpace(result.Output), "\n")
@@ -321,6 +413,12 @@ func PrintResults(results []ValidationResult) {
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	if allPassed {
 		fmt.Println("All validation checks passed!")
+	} else if failed := FailedGates(results); len(failed) > 0 {
+		names := make([]string, len(failed))
+		for i, r := range failed {
+			names[i] = r.Tool
+		}
+		fmt.Printf("FAILED: Required check(s) failed: %s\n", strings.Join(names, ", "))
 	} else {
 		fmt.Println("WARNING: Some validation checks failed. Review the output above.")
 	}
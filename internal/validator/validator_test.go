@@ -270,6 +270,78 @@ go 1.21
 	if !result.Success {
 		t.Errorf("Expected tests to pass, but they failed: %v", result.Error)
 	}
+
+	// Each test is reported individually
+	if result.Tests == nil || len(result.Tests.Tests) != 1 {
+		t.Fatalf("Expected 1 test result, got %+v", result.Tests)
+	}
+	if got := result.Tests.Tests[0]; got.Test != "TestExample" || got.Status != TestPass {
+		t.Errorf("Unexpected test result: %+v", got)
+	}
+}
+
+// TestRunTestsFailures verifies failing tests and test build errors are reported
+func TestRunTestsFailures(t *testing.T) {
+	workDir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/todo\n\ngo 1.21\n",
+		"models/todo_test.go": `package models
+
+import "testing"
+
+func TestPasses(t *testing.T) {}
+
+func TestFails(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		t.Error("title is required")
+	})
+}
+`,
+		"handlers/todo_test.go": `package handlers
+
+import "testing"
+
+func TestBroken(t *testing.T) {
+	var count int = "one"
+}
+`,
+	})
+
+	result := NewValidator(workDir).RunTests(context.Background())
+	if result.Error != nil && strings.Contains(result.Error.Error(), "executable file not found") {
+		t.Skip("go test not available on test system")
+	}
+	if result.Success || result.Tests == nil {
+		t.Fatalf("Expected failed tests with a report, got %+v", result)
+	}
+
+	statuses := make(map[string]string)
+	for _, r := range result.Tests.Tests {
+		statuses[r.Test] = r.Status
+	}
+	want := map[string]string{"TestPasses": TestPass, "TestFails": TestFail, "TestFails/empty": TestFail}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("Expected %s to %s, got %q", name, status, statuses[name])
+		}
+	}
+
+	failed := result.Tests.Failed()
+	var sawOutput, sawBuild bool
+	for _, r := range failed {
+		if r.Test == "TestFails/empty" && strings.Contains(r.Output, "title is required") {
+			sawOutput = true
+		}
+		if r.Test == "" && r.Package == "example.com/todo/handlers" {
+			sawBuild = true
+		}
+	}
+	if !sawOutput || !sawBuild {
+		t.Errorf("Expected the failing subtest and the broken package, got %+v", failed)
+	}
+
+	if len(result.Diagnostics) == 0 || result.Diagnostics[0].File != "handlers/todo_test.go" {
+		t.Errorf("Expected a diagnostic in handlers/todo_test.go, got %+v", result.Diagnostics)
+	}
 }
 
 // TestTimeoutHandling verifies timeout is applied correctly
@@ -0,0 +1,9 @@
+// Package prompts holds the default prompt templates compiled into the binary
+package prompts
+
+import "embed"
+
+// FS contains the default prompt templates, keyed by file name
+//
+//go:embed *.txt
+var FS embed.FS